@@ -14,6 +14,7 @@ import (
 	"lotbuy-backend/internal/auth"
 	"lotbuy-backend/internal/config"
 	"lotbuy-backend/internal/handlers"
+	"lotbuy-backend/internal/jobs"
 	"lotbuy-backend/internal/server"
 	"lotbuy-backend/internal/store"
 )
@@ -52,6 +53,19 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	scheduler := jobs.NewScheduler()
+	scheduler.Every("publish-scheduled-requests", time.Minute, jobs.PublishScheduledRequests(store))
+	scheduler.Every("match-saved-searches", 15*time.Second, jobs.MatchSavedSearches(store))
+	scheduler.Every("geocode-requests", time.Hour, jobs.GeocodeRequests(store))
+	scheduler.Every("send-search-digests", time.Hour, jobs.SendSearchDigests(store))
+	scheduler.Every("recurring-requests", time.Minute, jobs.RunRecurringSeries(store, api.SeriesRequestParams))
+	scheduler.Every("prune-request-views", time.Hour, jobs.PruneRequestViews(store))
+	scheduler.Every("purge-deleted-requests", time.Hour, jobs.PurgeDeletedRequests(store))
+	scheduler.Every("expire-offers", time.Minute, jobs.ExpireOffers(store))
+	scheduler.Every("close-auctions", 15*time.Second, jobs.CloseAuctions(store))
+	scheduler.Every("reveal-sealed-offers", time.Minute, jobs.RevealSealedOffers(store))
+	scheduler.Start(ctx)
+
 	go func() {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -71,7 +85,8 @@ func withCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PATCH,PUT,DELETE,OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, If-Match, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
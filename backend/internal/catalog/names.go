@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+const DefaultLanguage = "en"
+
+// LocalizedName picks the name for lang from a JSON object of translations,
+// falling back to the default language and then to any available entry.
+func LocalizedName(raw []byte, lang string) string {
+	var names map[string]string
+	if err := json.Unmarshal(raw, &names); err != nil || len(names) == 0 {
+		return ""
+	}
+	if name, ok := names[lang]; ok {
+		return name
+	}
+	if name, ok := names[DefaultLanguage]; ok {
+		return name
+	}
+	langs := make([]string, 0, len(names))
+	for l := range names {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return names[langs[0]]
+}
+
+// MatchesName reports whether value equals any translation, ignoring case.
+func MatchesName(raw []byte, value string) bool {
+	var names map[string]string
+	if err := json.Unmarshal(raw, &names); err != nil {
+		return false
+	}
+	for _, name := range names {
+		if strings.EqualFold(strings.TrimSpace(name), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreferredLanguage extracts the primary language subtag from an
+// Accept-Language header value.
+func PreferredLanguage(header string) string {
+	first := strings.TrimSpace(strings.Split(header, ",")[0])
+	first = strings.Split(first, ";")[0]
+	first = strings.Split(first, "-")[0]
+	if first == "" || first == "*" {
+		return DefaultLanguage
+	}
+	return strings.ToLower(first)
+}
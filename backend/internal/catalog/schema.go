@@ -0,0 +1,161 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AttributeSchema describes the structured attributes a lot in a category may
+// carry. It follows a small subset of JSON Schema: typed properties with
+// optional enum and range constraints, plus a list of required keys.
+type AttributeSchema struct {
+	Properties map[string]AttributeProperty `json:"properties,omitempty"`
+	Required   []string                     `json:"required,omitempty"`
+}
+
+type AttributeProperty struct {
+	Type      string            `json:"type"`
+	Title     map[string]string `json:"title,omitempty"`
+	Enum      []interface{}     `json:"enum,omitempty"`
+	Minimum   *float64          `json:"minimum,omitempty"`
+	Maximum   *float64          `json:"maximum,omitempty"`
+	MaxLength *int              `json:"maxLength,omitempty"`
+}
+
+func ParseSchema(raw []byte) (AttributeSchema, error) {
+	var schema AttributeSchema
+	if len(raw) == 0 {
+		return schema, nil
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return schema, err
+	}
+	return schema, nil
+}
+
+// Merge returns a schema containing the properties of both schemas. Properties
+// of child override those of s with the same name.
+func (s AttributeSchema) Merge(child AttributeSchema) AttributeSchema {
+	merged := AttributeSchema{Properties: make(map[string]AttributeProperty, len(s.Properties)+len(child.Properties))}
+	for name, prop := range s.Properties {
+		merged.Properties[name] = prop
+	}
+	for name, prop := range child.Properties {
+		merged.Properties[name] = prop
+	}
+	seen := make(map[string]bool)
+	for _, name := range append(append([]string{}, s.Required...), child.Required...) {
+		if !seen[name] {
+			seen[name] = true
+			merged.Required = append(merged.Required, name)
+		}
+	}
+	return merged
+}
+
+// Validate checks attrs against the schema. Unknown attributes are rejected.
+// When partial is true, missing required attributes are tolerated, which is
+// how drafts are saved.
+func (s AttributeSchema) Validate(attrs map[string]interface{}, partial bool) error {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			return fmt.Errorf("unknown attribute %q", name)
+		}
+		if err := prop.validate(attrs[name]); err != nil {
+			return fmt.Errorf("attribute %q %v", name, err)
+		}
+	}
+
+	if partial {
+		return nil
+	}
+	for _, name := range s.Required {
+		if value, ok := attrs[name]; !ok || value == nil {
+			return fmt.Errorf("attribute %q is required", name)
+		}
+	}
+	return nil
+}
+
+func (p AttributeProperty) validate(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var number float64
+	switch p.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		if p.MaxLength != nil && len([]rune(str)) > *p.MaxLength {
+			return fmt.Errorf("must be at most %d characters", *p.MaxLength)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+	case "integer", "number":
+		n, ok := toFloat(value)
+		if !ok {
+			return fmt.Errorf("must be a number")
+		}
+		if p.Type == "integer" && n != float64(int64(n)) {
+			return fmt.Errorf("must be an integer")
+		}
+		if p.Minimum != nil && n < *p.Minimum {
+			return fmt.Errorf("must be at least %v", *p.Minimum)
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			return fmt.Errorf("must be at most %v", *p.Maximum)
+		}
+		number = n
+	default:
+		return fmt.Errorf("has unsupported schema type %q", p.Type)
+	}
+
+	if len(p.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range p.Enum {
+		if p.Type == "integer" || p.Type == "number" {
+			if n, ok := toFloat(allowed); ok && n == number {
+				return nil
+			}
+			continue
+		}
+		if allowed == value {
+			return nil
+		}
+	}
+	options := make([]string, 0, len(p.Enum))
+	for _, allowed := range p.Enum {
+		options = append(options, fmt.Sprint(allowed))
+	}
+	return fmt.Errorf("must be one of: %s", strings.Join(options, ", "))
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,55 @@
+// Package currency converts amounts between currencies using a bundled table
+// of reference exchange rates. The rates are approximate and meant for
+// comparing offers, not for settling payments.
+package currency
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed rates.csv
+var ratesCSV string
+
+var (
+	ratesOnce sync.Once
+	perUSD    map[string]float64
+)
+
+func loadRates() map[string]float64 {
+	ratesOnce.Do(func() {
+		records, err := csv.NewReader(strings.NewReader(ratesCSV)).ReadAll()
+		if err != nil {
+			panic("currency: invalid bundled rate table: " + err.Error())
+		}
+		perUSD = make(map[string]float64, len(records)-1)
+		for _, rec := range records[1:] {
+			rate, err := strconv.ParseFloat(rec[1], 64)
+			if err != nil || rate <= 0 {
+				panic("currency: invalid rate for " + rec[0])
+			}
+			perUSD[rec[0]] = rate
+		}
+	})
+	return perUSD
+}
+
+// Convert converts amount from one currency to another. It reports false if
+// either currency is missing from the rate table.
+func Convert(amount float64, from, to string) (float64, bool) {
+	from = strings.ToUpper(strings.TrimSpace(from))
+	to = strings.ToUpper(strings.TrimSpace(to))
+	if from == to {
+		return amount, true
+	}
+	rates := loadRates()
+	fromRate, okFrom := rates[from]
+	toRate, okTo := rates[to]
+	if !okFrom || !okTo {
+		return 0, false
+	}
+	return amount / fromRate * toRate, true
+}
@@ -0,0 +1,37 @@
+package currency
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		from, to string
+		want     float64
+		wantOK   bool
+	}{
+		{"same currency", 12.5, "EUR", "EUR", 12.5, true},
+		{"same currency without a rate", 7, "XYZ", "xyz", 7, true},
+		{"from usd", 100, "USD", "EUR", 92, true},
+		{"to usd", 92.5, "RUB", "USD", 1, true},
+		{"between non-usd currencies", 92, "EUR", "RUB", 9250, true},
+		{"normalizes codes", 100, " usd ", "eur", 92, true},
+		{"zero amount", 0, "USD", "EUR", 0, true},
+		{"missing source rate", 100, "XYZ", "USD", 0, false},
+		{"missing target rate", 100, "USD", "XYZ", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Convert(tt.amount, tt.from, tt.to)
+			if ok != tt.wantOK {
+				t.Fatalf("Convert(%v, %q, %q) ok = %v, want %v", tt.amount, tt.from, tt.to, ok, tt.wantOK)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("Convert(%v, %q, %q) = %v, want %v", tt.amount, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
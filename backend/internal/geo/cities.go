@@ -0,0 +1,82 @@
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed cities.csv
+var citiesCSV string
+
+type city struct {
+	names       []string
+	countryCode string
+	country     string
+	point       Point
+}
+
+var (
+	citiesOnce sync.Once
+	citiesList []city
+)
+
+func loadCities() []city {
+	citiesOnce.Do(func() {
+		records, err := csv.NewReader(strings.NewReader(citiesCSV)).ReadAll()
+		if err != nil {
+			panic("geo: invalid bundled city dataset: " + err.Error())
+		}
+		for _, rec := range records[1:] {
+			lat, errLat := strconv.ParseFloat(rec[4], 64)
+			lng, errLng := strconv.ParseFloat(rec[5], 64)
+			if errLat != nil || errLng != nil {
+				panic("geo: invalid coordinates for " + rec[0])
+			}
+			citiesList = append(citiesList, city{
+				names:       []string{strings.ToLower(rec[0]), strings.ToLower(rec[1])},
+				countryCode: strings.ToLower(rec[2]),
+				country:     strings.ToLower(rec[3]),
+				point:       Point{Latitude: lat, Longitude: lng},
+			})
+		}
+	})
+	return citiesList
+}
+
+// LookupCity geocodes a city using the bundled offline dataset. Names match in
+// English or Russian regardless of case. A country given as an ISO code or
+// English name narrows the match; countries unknown to the dataset are
+// ignored so free-text input such as a localized country name still resolves.
+func LookupCity(name, country string) (Point, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	country = strings.ToLower(strings.TrimSpace(country))
+	if name == "" {
+		return Point{}, false
+	}
+	if country != "" && !knownCountry(country) {
+		country = ""
+	}
+	for _, c := range loadCities() {
+		if country != "" && country != c.countryCode && country != c.country {
+			continue
+		}
+		for _, n := range c.names {
+			if n == name {
+				return c.point, true
+			}
+		}
+	}
+	return Point{}, false
+}
+
+func knownCountry(country string) bool {
+	for _, c := range loadCities() {
+		if country == c.countryCode || country == c.country {
+			return true
+		}
+	}
+	return false
+}
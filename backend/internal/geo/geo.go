@@ -0,0 +1,70 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const earthRadiusKm = 6371.0
+
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// DistanceKm returns the great-circle distance between two points using the
+// haversine formula.
+func DistanceKm(a, b Point) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundingBox returns the latitude/longitude ranges that contain every point
+// within radiusKm of center. It is used to pre-filter rows with an index before
+// computing exact distances. Longitude bounds are clamped rather than wrapped
+// at the antimeridian.
+func BoundingBox(center Point, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat = math.Max(-90, center.Latitude-dLat)
+	maxLat = math.Min(90, center.Latitude+dLat)
+
+	if minLat <= -90 || maxLat >= 90 {
+		return minLat, maxLat, -180, 180
+	}
+	dLng := dLat / math.Cos(center.Latitude*math.Pi/180)
+	minLng = math.Max(-180, center.Longitude-dLng)
+	maxLng = math.Min(180, center.Longitude+dLng)
+	return minLat, maxLat, minLng, maxLng
+}
+
+// ParsePoint parses a "lat,lng" pair as used by the near= query parameter.
+func ParsePoint(value string) (Point, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return Point{}, errors.New("expected lat,lng")
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return Point{}, errors.New("invalid latitude")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return Point{}, errors.New("invalid longitude")
+	}
+	p := Point{Latitude: lat, Longitude: lng}
+	if !p.Valid() {
+		return Point{}, errors.New("coordinates out of range")
+	}
+	return p, nil
+}
@@ -35,18 +35,52 @@ func (a *API) RegisterRoutes(r *server.Router) {
 
 	r.Handle(http.MethodGet, "/api/me", a.handleGetMe)
 	r.Handle(http.MethodPatch, "/api/me", a.handleUpdateMe)
+	r.Handle(http.MethodGet, "/api/me/watchlist", a.handleListWatchlist)
+	r.Handle(http.MethodGet, "/api/me/deleted-requests", a.handleListDeletedRequests)
+	r.Handle(http.MethodGet, "/api/me/offer-score-weights", a.handleGetScoreWeights)
+	r.Handle(http.MethodPatch, "/api/me/offer-score-weights", a.handleUpdateScoreWeights)
 
 	r.Handle(http.MethodGet, "/api/dashboard", a.handleGetDashboard)
 
+	r.Handle(http.MethodGet, "/api/categories", a.handleListCategories)
+
 	r.Handle(http.MethodGet, "/api/requests", a.handleListRequests)
 	r.Handle(http.MethodPost, "/api/requests", a.handleCreateRequest)
+	r.Handle(http.MethodPost, "/api/requests/import", a.handleImportRequests)
 	r.Handle(http.MethodPatch, "/api/requests/:requestID", a.handleUpdateRequest)
 	r.Handle(http.MethodDelete, "/api/requests/:requestID", a.handleDeleteRequest)
 	r.Handle(http.MethodGet, "/api/requests/:requestID", a.handleGetRequest)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/publish", a.handlePublishRequest)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/restore", a.handleRestoreRequest)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/duplicate", a.handleDuplicateRequest)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/template", a.handleSaveRequestAsTemplate)
+	r.Handle(http.MethodGet, "/api/requests/:requestID/history", a.handleRequestHistory)
+	r.Handle(http.MethodGet, "/api/requests/:requestID/stats", a.handleRequestStats)
+	r.Handle(http.MethodGet, "/api/requests/:requestID/invitations", a.handleListRequestInvitations)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/invitations", a.handleInviteSellers)
+	r.Handle(http.MethodDelete, "/api/requests/:requestID/invitations/:invitationID", a.handleDeleteRequestInvitation)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/share-link", a.handleCreateShareLink)
+	r.Handle(http.MethodDelete, "/api/requests/:requestID/share-link", a.handleRevokeShareLink)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/watch", a.handleWatchRequest)
+	r.Handle(http.MethodDelete, "/api/requests/:requestID/watch", a.handleUnwatchRequest)
+	r.Handle(http.MethodGet, "/api/requests/:requestID/images", a.handleListRequestImages)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/images", a.handleAddRequestImage)
+	r.Handle(http.MethodPost, "/api/requests/:requestID/images/reorder", a.handleReorderRequestImages)
+	r.Handle(http.MethodPatch, "/api/requests/:requestID/images/:imageID", a.handleUpdateRequestImage)
+	r.Handle(http.MethodDelete, "/api/requests/:requestID/images/:imageID", a.handleDeleteRequestImage)
 	r.Handle(http.MethodGet, "/api/requests/:requestID/offers", a.handleListOffers)
 	r.Handle(http.MethodPost, "/api/requests/:requestID/offers", a.handleCreateOffer)
+	r.Handle(http.MethodGet, "/api/requests/:requestID/offers/compare", a.handleCompareOffers)
+	r.Handle(http.MethodGet, "/api/requests/:requestID/auction", a.handleGetAuction)
 
+	r.Handle(http.MethodPatch, "/api/offers/:offerID", a.handleUpdateOffer)
+	r.Handle(http.MethodPost, "/api/offers/:offerID/withdraw", a.handleWithdrawOffer)
+	r.Handle(http.MethodPost, "/api/offers/:offerID/extend", a.handleExtendOffer)
+	r.Handle(http.MethodPost, "/api/offers/:offerID/reject", a.handleRejectOffer)
 	r.Handle(http.MethodPost, "/api/offers/:offerID/accept", a.handleAcceptOffer)
+	r.Handle(http.MethodPost, "/api/offers/:offerID/counter", a.handleCounterOffer)
+	r.Handle(http.MethodPost, "/api/offers/:offerID/decline", a.handleDeclineOffer)
+	r.Handle(http.MethodGet, "/api/offers/:offerID/rounds", a.handleListOfferRounds)
 	r.Handle(http.MethodGet, "/api/offers/:offerID/messages", a.handleListOfferMessages)
 	r.Handle(http.MethodPost, "/api/offers/:offerID/messages", a.handleCreateOfferMessage)
 
@@ -55,6 +89,30 @@ func (a *API) RegisterRoutes(r *server.Router) {
 	r.Handle(http.MethodPatch, "/api/deals/:dealID", a.handleUpdateDeal)
 	r.Handle(http.MethodPost, "/api/deals/:dealID/milestones/:milestoneID/complete", a.handleCompleteMilestone)
 
+	r.Handle(http.MethodGet, "/api/request-templates", a.handleListRequestTemplates)
+	r.Handle(http.MethodPost, "/api/request-templates", a.handleCreateRequestTemplate)
+	r.Handle(http.MethodGet, "/api/request-templates/:templateID", a.handleGetRequestTemplate)
+	r.Handle(http.MethodPatch, "/api/request-templates/:templateID", a.handleUpdateRequestTemplate)
+	r.Handle(http.MethodDelete, "/api/request-templates/:templateID", a.handleDeleteRequestTemplate)
+	r.Handle(http.MethodPost, "/api/request-templates/:templateID/requests", a.handleCreateRequestFromTemplate)
+
+	r.Handle(http.MethodGet, "/api/request-series", a.handleListRequestSeries)
+	r.Handle(http.MethodPost, "/api/request-series", a.handleCreateRequestSeries)
+	r.Handle(http.MethodGet, "/api/request-series/:seriesID", a.handleGetRequestSeries)
+	r.Handle(http.MethodPatch, "/api/request-series/:seriesID", a.handleUpdateRequestSeries)
+	r.Handle(http.MethodDelete, "/api/request-series/:seriesID", a.handleDeleteRequestSeries)
+
+	r.Handle(http.MethodGet, "/api/organizations", a.handleListOrganizations)
+	r.Handle(http.MethodPost, "/api/organizations", a.handleCreateOrganization)
+	r.Handle(http.MethodGet, "/api/organizations/:organizationID/members", a.handleListOrganizationMembers)
+	r.Handle(http.MethodPost, "/api/organizations/:organizationID/members", a.handleAddOrganizationMember)
+	r.Handle(http.MethodDelete, "/api/organizations/:organizationID/members/:userID", a.handleRemoveOrganizationMember)
+
+	r.Handle(http.MethodGet, "/api/saved-searches", a.handleListSavedSearches)
+	r.Handle(http.MethodPost, "/api/saved-searches", a.handleCreateSavedSearch)
+	r.Handle(http.MethodPatch, "/api/saved-searches/:searchID", a.handleUpdateSavedSearch)
+	r.Handle(http.MethodDelete, "/api/saved-searches/:searchID", a.handleDeleteSavedSearch)
+
 	r.Handle(http.MethodGet, "/api/notifications", a.handleListNotifications)
 	r.Handle(http.MethodPost, "/api/notifications/:notificationID/read", a.handleMarkNotificationRead)
 
@@ -100,6 +158,28 @@ func (a *API) requireAuth(w http.ResponseWriter, r *http.Request) (*models.User,
 	return user, true
 }
 
+// optionalUser resolves the caller from the Authorization header when one is
+// present. Missing or invalid credentials yield nil instead of an error so
+// public endpoints can tailor their response to signed-in users.
+func (a *API) optionalUser(r *http.Request) *models.User {
+	if a.Tokens == nil {
+		return nil
+	}
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return nil
+	}
+	userID, expires, ok := a.Tokens.Parse(strings.TrimSpace(parts[1]))
+	if !ok || time.Now().After(expires) {
+		return nil
+	}
+	user, err := a.Store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
 func parseID(r *http.Request, key string) (int64, error) {
 	idStr := server.Param(r, key)
 	if idStr == "" {
@@ -289,8 +369,9 @@ func (a *API) handleGetMe(w http.ResponseWriter, r *http.Request) {
 
 	activeLimit := 5
 	requests, err := a.Store.ListRequests(r.Context(), store.ListRequestsParams{
-		BuyerID: &user.ID,
-		Limit:   &activeLimit,
+		BuyerID:  &user.ID,
+		ViewerID: &user.ID,
+		Limit:    &activeLimit,
 	})
 	if err != nil {
 		httputil.Error(w, http.StatusInternalServerError, "failed to load lots")
@@ -400,8 +481,9 @@ func (a *API) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
 
 	activeLimit := 4
 	activeLots, err := a.Store.ListRequests(r.Context(), store.ListRequestsParams{
-		BuyerID: &user.ID,
-		Limit:   &activeLimit,
+		BuyerID:  &user.ID,
+		ViewerID: &user.ID,
+		Limit:    &activeLimit,
 	})
 	if err != nil {
 		httputil.Error(w, http.StatusInternalServerError, "failed to load lots")
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/store"
+)
+
+// checkAuctionBidTerms verifies a bid on an auction lot is comparable with the
+// others: for the full quantity and in the lot currency.
+func checkAuctionBidTerms(req *models.Request, currencyCode string, quantity int) error {
+	if !req.IsAuction() {
+		return nil
+	}
+	if !strings.EqualFold(strings.TrimSpace(currencyCode), req.CurrencyCode) {
+		return fmt.Errorf("auction bids must be in %s", req.CurrencyCode)
+	}
+	if quantity != req.Quantity {
+		return fmt.Errorf("auction bids must cover the full %d %s", req.Quantity, req.Unit)
+	}
+	return nil
+}
+
+// writeAuctionBidError reports bids the auction rules refuse, with the highest
+// acceptable amount unless the lot keeps prices private, and falls back to
+// writeOfferTransitionError.
+func (a *API) writeAuctionBidError(w http.ResponseWriter, r *http.Request, req *models.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrAuctionClosed), errors.Is(err, store.ErrBiddingClosed):
+		httputil.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, store.ErrBidTooHigh):
+		if req.OfferVisibility == "private" {
+			httputil.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		state, stateErr := a.Store.GetAuctionState(r.Context(), req, nil)
+		if stateErr != nil || state.MaxNextBid == nil {
+			httputil.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		httputil.Error(w, http.StatusConflict, "bid must be at most "+formatAmount(*state.MaxNextBid, req.CurrencyCode))
+	default:
+		writeOfferTransitionError(w, err)
+	}
+}
+
+// auctionLeader returns the seller of the lowest bid on an auction lot, if
+// any, so they can be told once they are outbid.
+func (a *API) auctionLeader(ctx context.Context, req *models.Request) *int64 {
+	if !req.IsAuction() {
+		return nil
+	}
+	// Auction bids are never sealed, so the anonymous listing has them all.
+	offers, err := a.Store.ListOffersByRequest(ctx, req.ID, nil)
+	if err != nil {
+		return nil
+	}
+	var leader *models.Offer
+	for i := range offers {
+		o := &offers[i]
+		if o.Status != "pending" {
+			continue
+		}
+		if leader == nil || o.PriceAmount < leader.PriceAmount ||
+			(o.PriceAmount == leader.PriceAmount && o.CreatedAt.Before(leader.CreatedAt)) {
+			leader = o
+		}
+	}
+	if leader == nil {
+		return nil
+	}
+	return leader.SellerID
+}
+
+// notifyOutbid tells the previous leader of an auction that bid undercut them.
+func (a *API) notifyOutbid(ctx context.Context, req *models.Request, previousLeader *int64, bid *models.Offer) {
+	if previousLeader == nil || (bid.SellerID != nil && *bid.SellerID == *previousLeader) {
+		return
+	}
+	body := "Новая ставка по лоту " + req.Title
+	if req.OfferVisibility != "private" {
+		body += ": " + formatAmount(bid.PriceAmount, bid.CurrencyCode)
+	}
+	meta, _ := json.Marshal(map[string]interface{}{"requestId": req.ID})
+	_, _ = a.Store.CreateNotification(ctx, store.CreateNotificationParams{
+		UserID:   *previousLeader,
+		Type:     "auction.outbid",
+		Title:    "Вашу ставку перебили",
+		Body:     &body,
+		Metadata: meta,
+	})
+}
+
+// handleGetAuction shows the anonymized state of an auction lot.
+func (a *API) handleGetAuction(w http.ResponseWriter, r *http.Request) {
+	requestID, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	user := a.optionalUser(r)
+	req, err := a.Store.GetRequest(r.Context(), requestID)
+	if err != nil || !a.canViewRequest(r, req, user) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	if !req.IsAuction() {
+		httputil.Error(w, http.StatusNotFound, "lot is not an auction")
+		return
+	}
+
+	var viewerID *int64
+	if user != nil {
+		viewerID = &user.ID
+	}
+	state, err := a.Store.GetAuctionState(r.Context(), req, viewerID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if state.MaxNextBid != nil {
+		rounded := math.Round(*state.MaxNextBid*100) / 100
+		state.MaxNextBid = &rounded
+	}
+	applyAuctionVisibility(req, user, state)
+	httputil.JSON(w, http.StatusOK, state)
+}
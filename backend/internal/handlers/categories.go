@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/catalog"
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+)
+
+// categorySelection is a validated category/subcategory pair together with the
+// attribute schema that applies to lots filed under it.
+type categorySelection struct {
+	Category    *string
+	Subcategory *string
+	Schema      catalog.AttributeSchema
+}
+
+func (a *API) handleListCategories(w http.ResponseWriter, r *http.Request) {
+	lang := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("lang")))
+	if lang == "" {
+		lang = catalog.PreferredLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	categories, err := a.Store.ListCategories(r.Context())
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "failed to load categories")
+		return
+	}
+
+	httputil.JSON(w, http.StatusOK, buildCategoryTree(categories, lang))
+}
+
+func buildCategoryTree(categories []models.Category, lang string) []models.Category {
+	children := make(map[int64][]models.Category)
+	roots := make([]models.Category, 0)
+	for _, c := range categories {
+		c.Name = catalog.LocalizedName(c.Names, lang)
+		if c.ParentID == nil {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+	for i := range roots {
+		roots[i].Children = children[roots[i].ID]
+	}
+	return roots
+}
+
+// validationError marks a failure caused by the client's input, as opposed to
+// an internal error.
+type validationError string
+
+func (e validationError) Error() string { return string(e) }
+
+// writeValidationError responds 400 for validation errors and 500 otherwise.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	httputil.Error(w, http.StatusInternalServerError, err.Error())
+}
+
+// resolveCategory maps user input to category slugs, accepting either a slug
+// or a localized name in any case, and checks that the subcategory belongs to
+// the category.
+func (a *API) resolveCategory(w http.ResponseWriter, r *http.Request, category, subcategory *string) (categorySelection, bool) {
+	selection, err := a.selectCategory(r.Context(), category, subcategory)
+	if err != nil {
+		writeValidationError(w, err)
+		return selection, false
+	}
+	return selection, true
+}
+
+func (a *API) selectCategory(ctx context.Context, category, subcategory *string) (categorySelection, error) {
+	var selection categorySelection
+	if category == nil {
+		if subcategory != nil {
+			return selection, validationError("subcategory requires a category")
+		}
+		return selection, nil
+	}
+
+	categories, err := a.Store.ListCategories(ctx)
+	if err != nil {
+		return selection, errors.New("failed to load categories")
+	}
+
+	parent := findCategory(categories, nil, *category)
+	if parent == nil {
+		return selection, validationError("unknown category " + *category)
+	}
+	schema, err := catalog.ParseSchema(parent.AttributeSchema)
+	if err != nil {
+		return selection, errors.New("invalid attribute schema for category " + parent.Slug)
+	}
+	selection.Category = &parent.Slug
+
+	if subcategory != nil {
+		child := findCategory(categories, &parent.ID, *subcategory)
+		if child == nil {
+			return selection, validationError("unknown subcategory " + *subcategory + " for category " + parent.Slug)
+		}
+		childSchema, err := catalog.ParseSchema(child.AttributeSchema)
+		if err != nil {
+			return selection, errors.New("invalid attribute schema for category " + child.Slug)
+		}
+		schema = schema.Merge(childSchema)
+		selection.Subcategory = &child.Slug
+	}
+
+	selection.Schema = schema
+	return selection, nil
+}
+
+func findCategory(categories []models.Category, parentID *int64, value string) *models.Category {
+	needle := strings.ToLower(strings.TrimSpace(value))
+	for i := range categories {
+		c := &categories[i]
+		sameParent := (parentID == nil && c.ParentID == nil) ||
+			(parentID != nil && c.ParentID != nil && *parentID == *c.ParentID)
+		if !sameParent {
+			continue
+		}
+		if c.Slug == needle || catalog.MatchesName(c.Names, needle) {
+			return c
+		}
+	}
+	return nil
+}
+
+// validateAttributes checks raw lot attributes against the selected schema and
+// returns them re-encoded, or nil when no attributes were given.
+func validateAttributes(w http.ResponseWriter, selection categorySelection, raw json.RawMessage, partial bool) ([]byte, bool) {
+	encoded, err := checkAttributes(selection, raw, partial)
+	if err != nil {
+		writeValidationError(w, err)
+		return nil, false
+	}
+	return encoded, true
+}
+
+func checkAttributes(selection categorySelection, raw json.RawMessage, partial bool) ([]byte, error) {
+	attrs := map[string]interface{}{}
+	if len(raw) > 0 && !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&attrs); err != nil {
+			return nil, validationError("attributes must be a JSON object")
+		}
+	}
+	if selection.Category == nil {
+		if len(attrs) > 0 {
+			return nil, validationError("attributes require a category")
+		}
+		return nil, nil
+	}
+	if err := selection.Schema.Validate(attrs, partial); err != nil {
+		return nil, validationError(err.Error())
+	}
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, errors.New("failed to encode attributes")
+	}
+	return encoded, nil
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
         "errors"
+        "fmt"
         "net/http"
         "strings"
 
@@ -50,7 +51,13 @@ func (a *API) handleGetDeal(w http.ResponseWriter, r *http.Request) {
                 httputil.Error(w, http.StatusForbidden, "not allowed to view this deal")
                 return
         }
-        httputil.JSON(w, http.StatusOK, deal)
+        httputil.JSONWithETag(w, r, http.StatusOK, deal, dealETag(deal))
+}
+
+// dealETag identifies the version of a deal as returned by the API, which
+// embeds the lot.
+func dealETag(detail *models.DealDetails) string {
+        return httputil.ETag(fmt.Sprintf("d%d-%d-%d", detail.ID, detail.UpdatedAt.UnixMicro(), detail.Request.UpdatedAt.UnixMicro()))
 }
 
 func (a *API) handleUpdateDeal(w http.ResponseWriter, r *http.Request) {
@@ -70,19 +77,41 @@ func (a *API) handleUpdateDeal(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
+        // The version the caller saw is checked again by the store once the
+        // deal is locked, so a concurrent change cannot slip in between.
+        var ifVersion *store.DealVersion
+        if r.Header.Get("If-Match") != "" {
+                current, err := a.Store.GetDealDetails(r.Context(), dealID)
+                if err != nil {
+                        httputil.Error(w, http.StatusNotFound, err.Error())
+                        return
+                }
+                if !a.canAccessDeal(current, user.ID) {
+                        httputil.Error(w, http.StatusForbidden, "not allowed to update this deal")
+                        return
+                }
+                if !httputil.IfMatch(r, dealETag(current)) {
+                        httputil.PreconditionFailed(w, dealETag(current))
+                        return
+                }
+                ifVersion = &store.DealVersion{UpdatedAt: current.UpdatedAt, RequestUpdatedAt: current.Request.UpdatedAt}
+        }
+
         var detail *models.DealDetails
         switch payload.Action {
         case "mark_shipped":
-                detail, err = a.Store.MarkDealShipped(r.Context(), dealID, user.ID)
+                detail, err = a.Store.MarkDealShipped(r.Context(), dealID, user.ID, ifVersion)
         case "submit_payment":
-                detail, err = a.Store.SubmitDealPayment(r.Context(), dealID, user.ID)
+                detail, err = a.Store.SubmitDealPayment(r.Context(), dealID, user.ID, ifVersion)
         case "confirm_delivery":
-                detail, err = a.Store.ConfirmDealCompletion(r.Context(), dealID, user.ID)
+                detail, err = a.Store.ConfirmDealCompletion(r.Context(), dealID, user.ID, ifVersion)
         case "open_dispute":
                 if payload.Reason == "" {
                         payload.Reason = "Deal dispute opened"
                 }
-                detail, err = a.Store.OpenDealDispute(r.Context(), dealID, user.ID, payload.Reason)
+                detail, err = a.Store.OpenDealDispute(r.Context(), dealID, user.ID, payload.Reason, ifVersion)
+        case "cancel":
+                detail, err = a.Store.CancelDeal(r.Context(), dealID, user.ID, payload.Reason, ifVersion)
         case "rate_counterparty":
                 if payload.Rating == nil {
                         httputil.Error(w, http.StatusBadRequest, "rating is required")
@@ -98,6 +127,7 @@ func (a *API) handleUpdateDeal(w http.ResponseWriter, r *http.Request) {
                         ReviewerID: user.ID,
                         Rating:     *payload.Rating,
                         Comment:    commentPtr,
+                        IfVersion:  ifVersion,
                 })
         default:
                 httputil.Error(w, http.StatusBadRequest, "unsupported action")
@@ -108,13 +138,19 @@ func (a *API) handleUpdateDeal(w http.ResponseWriter, r *http.Request) {
                 status := http.StatusInternalServerError
                 if errors.Is(err, store.ErrDealUnauthorized) {
                         status = http.StatusForbidden
-                } else if errors.Is(err, store.ErrMilestoneDone) {
+                } else if errors.Is(err, store.ErrMilestoneDone) || errors.Is(err, store.ErrDealCancelled) || errors.Is(err, store.ErrDealNotCancellable) {
                         status = http.StatusConflict
+                } else if errors.Is(err, store.ErrDealModified) {
+                        if current, err := a.Store.GetDealDetails(r.Context(), dealID); err == nil {
+                                httputil.PreconditionFailed(w, dealETag(current))
+                                return
+                        }
+                        status = http.StatusPreconditionFailed
                 }
                 httputil.Error(w, status, err.Error())
                 return
         }
-        httputil.JSON(w, http.StatusOK, detail)
+        httputil.JSONWithETag(w, r, http.StatusOK, detail, dealETag(detail))
 }
 
 func (a *API) handleCompleteMilestone(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/store"
+)
+
+// defaultAuctionExtension is the anti-sniping window when the buyer does not
+// choose one: a bid in the last five minutes extends the auction by five
+// minutes from the bid.
+const defaultAuctionExtension = 5
+
+// maxBiddingHours bounds how long an auction or a sealed bidding round runs.
+const maxBiddingHours = 720
+
+var requestModes = map[string]bool{"standard": true, "reverse_auction": true, "sealed_bid": true}
+
+var auctionAwards = map[string]bool{"auto": true, "buyer": true}
+
+// lotModePayload holds the lot fields selecting how sellers compete. A
+// reverse auction ends at AuctionEndsAt or AuctionDurationHours after
+// publishing; sealed bids are revealed at SealedUntil or SealedDurationHours
+// after publishing.
+type lotModePayload struct {
+	Mode                    *string  `json:"mode,omitempty"`
+	AuctionEndsAt           *string  `json:"auctionEndsAt,omitempty"`
+	AuctionDurationHours    *int     `json:"auctionDurationHours,omitempty"`
+	AuctionMinDecrement     *float64 `json:"auctionMinDecrement,omitempty"`
+	AuctionExtensionMinutes *int     `json:"auctionExtensionMinutes,omitempty"`
+	AuctionAward            *string  `json:"auctionAward,omitempty"`
+	SealedUntil             *string  `json:"sealedUntil,omitempty"`
+	SealedDurationHours     *int     `json:"sealedDurationHours,omitempty"`
+}
+
+func (p lotModePayload) mode() string {
+	if p.Mode == nil || strings.TrimSpace(*p.Mode) == "" {
+		return "standard"
+	}
+	return strings.ToLower(strings.TrimSpace(*p.Mode))
+}
+
+func (p lotModePayload) hasAuctionSettings() bool {
+	return p.AuctionEndsAt != nil || p.AuctionDurationHours != nil || p.AuctionMinDecrement != nil ||
+		p.AuctionExtensionMinutes != nil || p.AuctionAward != nil
+}
+
+func (p lotModePayload) hasSealedSettings() bool {
+	return p.SealedUntil != nil || p.SealedDurationHours != nil
+}
+
+// validate checks the settings of the chosen mode. Drafts may leave out the
+// end and the decrement until they are published.
+func (p lotModePayload) validate(draft bool) error {
+	mode := p.mode()
+	if !requestModes[mode] {
+		return errors.New("mode must be one of standard, reverse_auction, sealed_bid")
+	}
+	if mode != "reverse_auction" && p.hasAuctionSettings() {
+		return errors.New("auction settings require mode reverse_auction")
+	}
+	if mode != "sealed_bid" && p.hasSealedSettings() {
+		return errors.New("sealedUntil and sealedDurationHours require mode sealed_bid")
+	}
+
+	switch mode {
+	case "reverse_auction":
+		if p.AuctionEndsAt != nil && p.AuctionDurationHours != nil {
+			return errors.New("provide either auctionEndsAt or auctionDurationHours")
+		}
+		if p.AuctionDurationHours != nil && (*p.AuctionDurationHours <= 0 || *p.AuctionDurationHours > maxBiddingHours) {
+			return errors.New("auctionDurationHours must be between 1 and 720")
+		}
+		if p.AuctionMinDecrement != nil && *p.AuctionMinDecrement <= 0 {
+			return errors.New("auctionMinDecrement must be greater than zero")
+		}
+		if p.AuctionExtensionMinutes != nil && (*p.AuctionExtensionMinutes < 0 || *p.AuctionExtensionMinutes > 60) {
+			return errors.New("auctionExtensionMinutes must be between 0 and 60")
+		}
+		if p.AuctionAward != nil && !auctionAwards[strings.ToLower(strings.TrimSpace(*p.AuctionAward))] {
+			return errors.New("auctionAward must be one of auto, buyer")
+		}
+		if draft {
+			return nil
+		}
+		if p.AuctionEndsAt == nil && p.AuctionDurationHours == nil {
+			return errors.New("auctionEndsAt or auctionDurationHours is required")
+		}
+		if p.AuctionMinDecrement == nil {
+			return errors.New("auctionMinDecrement is required")
+		}
+	case "sealed_bid":
+		if p.SealedUntil != nil && p.SealedDurationHours != nil {
+			return errors.New("provide either sealedUntil or sealedDurationHours")
+		}
+		if p.SealedDurationHours != nil && (*p.SealedDurationHours <= 0 || *p.SealedDurationHours > maxBiddingHours) {
+			return errors.New("sealedDurationHours must be between 1 and 720")
+		}
+		if !draft && p.SealedUntil == nil && p.SealedDurationHours == nil {
+			return errors.New("sealedUntil or sealedDurationHours is required")
+		}
+	}
+	return nil
+}
+
+// settings resolves validated mode settings for a lot created with status.
+// A fixed end must fall after the lot is published; an open lot starts its
+// bidding right away.
+func (p lotModePayload) settings(status string, publishAt *time.Time) (store.LotModeSettings, error) {
+	mode := p.mode()
+	if mode == "standard" {
+		return store.LotModeSettings{}, nil
+	}
+	start := time.Now()
+	if publishAt != nil {
+		start = *publishAt
+	}
+	// biddingEnd resolves a fixed end or a duration counted from start.
+	biddingEnd := func(field string, fixed *string, hours *int) (*time.Time, error) {
+		end, err := parseOptionalTime(fixed)
+		if err != nil {
+			return nil, errors.New(field + " must be an RFC3339 string")
+		}
+		if end != nil && !end.After(start) {
+			return nil, errors.New(field + " must be after the lot is published")
+		}
+		if end == nil && status == "open" && hours != nil {
+			ts := start.Add(time.Duration(*hours) * time.Hour)
+			end = &ts
+		}
+		return end, nil
+	}
+
+	settings := store.LotModeSettings{Mode: mode}
+	if mode == "sealed_bid" {
+		until, err := biddingEnd("sealedUntil", p.SealedUntil, p.SealedDurationHours)
+		if err != nil {
+			return store.LotModeSettings{}, err
+		}
+		settings.SealedUntil = until
+		settings.SealedDurationHours = p.SealedDurationHours
+		return settings, nil
+	}
+
+	endsAt, err := biddingEnd("auctionEndsAt", p.AuctionEndsAt, p.AuctionDurationHours)
+	if err != nil {
+		return store.LotModeSettings{}, err
+	}
+	extension := defaultAuctionExtension
+	if p.AuctionExtensionMinutes != nil {
+		extension = *p.AuctionExtensionMinutes
+	}
+	award := "buyer"
+	if p.AuctionAward != nil {
+		award = strings.ToLower(strings.TrimSpace(*p.AuctionAward))
+	}
+	settings.AuctionEndsAt = endsAt
+	settings.AuctionDurationHours = p.AuctionDurationHours
+	settings.AuctionMinDecrement = p.AuctionMinDecrement
+	settings.AuctionExtensionMinutes = &extension
+	settings.AuctionAward = &award
+	return settings, nil
+}
+
+// lotModePayloadFromRequest returns the mode settings of a lot for reuse,
+// without its fixed dates.
+func lotModePayloadFromRequest(req *models.Request) lotModePayload {
+	if req.Mode == "" || req.Mode == "standard" {
+		return lotModePayload{}
+	}
+	mode := req.Mode
+	if !req.IsAuction() {
+		return lotModePayload{Mode: &mode, SealedDurationHours: req.SealedDurationHours}
+	}
+	return lotModePayload{
+		Mode:                    &mode,
+		AuctionDurationHours:    req.AuctionDurationHours,
+		AuctionMinDecrement:     req.AuctionMinDecrement,
+		AuctionExtensionMinutes: req.AuctionExtensionMinutes,
+		AuctionAward:            req.AuctionAward,
+	}
+}
+
+// checkModePublish verifies a draft or scheduled lot has the settings its mode
+// needs to start at publishAt, or now when it is nil.
+func checkModePublish(req *models.Request, publishAt *time.Time) error {
+	start := time.Now()
+	if publishAt != nil && publishAt.After(start) {
+		start = *publishAt
+	}
+	switch {
+	case req.IsAuction():
+		if req.AuctionMinDecrement == nil {
+			return errors.New("auctionMinDecrement is required")
+		}
+		if req.AuctionEndsAt == nil && req.AuctionDurationHours == nil {
+			return errors.New("auctionEndsAt or auctionDurationHours is required")
+		}
+		if req.AuctionEndsAt != nil && !req.AuctionEndsAt.After(start) {
+			return errors.New("auctionEndsAt must be after the lot is published")
+		}
+	case req.IsSealed():
+		if req.SealedUntil == nil && req.SealedDurationHours == nil {
+			return errors.New("sealedUntil or sealedDurationHours is required")
+		}
+		if req.SealedUntil != nil && !req.SealedUntil.After(start) {
+			return errors.New("sealedUntil must be after the lot is published")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"lotbuy-backend/internal/currency"
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+)
+
+// defaultScoreWeights favour price, then delivery time and the seller's
+// rating, then their experience.
+var defaultScoreWeights = models.OfferScoreWeights{Price: 50, Delivery: 20, Rating: 20, Experience: 10}
+
+// parseScoreWeights reads the buyer's weights from the query string, falling
+// back to defaults, their saved weights, for criteria left out.
+func parseScoreWeights(r *http.Request, defaults models.OfferScoreWeights) (models.OfferScoreWeights, error) {
+	weights := defaults
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name   string
+		target *float64
+	}{
+		{"priceWeight", &weights.Price},
+		{"deliveryWeight", &weights.Delivery},
+		{"ratingWeight", &weights.Rating},
+		{"experienceWeight", &weights.Experience},
+	} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || !validScoreWeight(value) {
+			return weights, errors.New(param.name + " must be a non-negative number")
+		}
+		*param.target = value
+	}
+	if weights.Price+weights.Delivery+weights.Rating+weights.Experience == 0 {
+		return weights, errors.New("at least one weight must be greater than zero")
+	}
+	return weights, nil
+}
+
+func validScoreWeight(value float64) bool {
+	return value >= 0 && !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+// buyerScoreWeights returns the weights the user saved, or the defaults.
+func (a *API) buyerScoreWeights(ctx context.Context, userID int64) (models.OfferScoreWeights, error) {
+	saved, err := a.Store.GetOfferScoreWeights(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaultScoreWeights, nil
+	}
+	if err != nil {
+		return defaultScoreWeights, err
+	}
+	return *saved, nil
+}
+
+// compareOffers scores each offer from 0 to 100 on every criterion relative to
+// the best competing offer, and ranks them by the weighted average:
+//   - price: unit cost including shipping, converted to the lot currency;
+//   - delivery: promised delivery days;
+//   - rating: the seller's average rating out of 5;
+//   - experience: the seller's completed deals.
+//
+// Criteria an offer does not state score zero.
+func compareOffers(req *models.Request, offers []models.ComparableOffer, weights models.OfferScoreWeights) models.OfferComparison {
+	scored := make([]models.ScoredOffer, len(offers))
+	var minUnitCost, minDays *float64
+	maxDeals := 0
+	for i, o := range offers {
+		s := models.ScoredOffer{
+			Offer:                o.Offer,
+			SellerRating:         o.SellerAverageRating,
+			SellerCompletedDeals: o.SellerCompletedDeals,
+		}
+		if s.SellerRating == nil {
+			s.SellerRating = o.Offer.SellerRating
+		}
+
+		cost, ok := currency.Convert(o.PriceAmount, o.CurrencyCode, req.CurrencyCode)
+		if o.ShippingCost != nil && ok {
+			var shipping float64
+			shipping, ok = currency.Convert(*o.ShippingCost, o.CurrencyCode, req.CurrencyCode)
+			cost += shipping
+		}
+		if ok {
+			landed := roundCents(cost)
+			unit := cost
+			if o.Quantity > 0 {
+				unit = cost / float64(o.Quantity)
+			}
+			s.LandedCost = &landed
+			s.UnitLandedCost = &unit
+			if minUnitCost == nil || unit < *minUnitCost {
+				minUnitCost = &unit
+			}
+		} else {
+			s.Warnings = append(s.Warnings, "no exchange rate from "+o.CurrencyCode+" to "+req.CurrencyCode)
+		}
+		if o.ShippingCost == nil && (o.ShippingMethod == nil || *o.ShippingMethod != "pickup") {
+			s.Warnings = append(s.Warnings, "shipping cost not stated")
+		}
+
+		if o.DeliveryDays != nil {
+			days := float64(*o.DeliveryDays)
+			if minDays == nil || days < *minDays {
+				minDays = &days
+			}
+		}
+		if o.SellerCompletedDeals > maxDeals {
+			maxDeals = o.SellerCompletedDeals
+		}
+		scored[i] = s
+	}
+
+	total := weights.Price + weights.Delivery + weights.Rating + weights.Experience
+	for i := range scored {
+		s := &scored[i]
+		if s.UnitLandedCost != nil {
+			if *s.UnitLandedCost > 0 {
+				s.Breakdown.Price = roundScore(100 * *minUnitCost / *s.UnitLandedCost)
+			} else {
+				s.Breakdown.Price = 100
+			}
+			unit := roundCents(*s.UnitLandedCost)
+			s.UnitLandedCost = &unit
+		}
+		if s.Offer.DeliveryDays != nil {
+			s.Breakdown.Delivery = roundScore(100 * (*minDays + 1) / (float64(*s.Offer.DeliveryDays) + 1))
+		}
+		if s.SellerRating != nil {
+			s.Breakdown.Rating = roundScore(math.Min(*s.SellerRating, 5) * 20)
+		}
+		if maxDeals > 0 {
+			s.Breakdown.Experience = roundScore(100 * float64(s.SellerCompletedDeals) / float64(maxDeals))
+		}
+		s.Score = roundScore((weights.Price*s.Breakdown.Price +
+			weights.Delivery*s.Breakdown.Delivery +
+			weights.Rating*s.Breakdown.Rating +
+			weights.Experience*s.Breakdown.Experience) / total)
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	comparison := models.OfferComparison{
+		RequestID:    req.ID,
+		CurrencyCode: req.CurrencyCode,
+		Weights:      weights,
+		Offers:       scored,
+	}
+	for i := range scored {
+		scored[i].Rank = i + 1
+		// An offer whose price cannot be compared is never the best value.
+		if comparison.BestValueID == nil && scored[i].LandedCost != nil {
+			id := scored[i].Offer.ID
+			comparison.BestValueID = &id
+		}
+	}
+	return comparison
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func roundScore(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+// handleCompareOffers ranks the open offers on a lot for its owner, by the
+// weights in the query string or else the ones the owner saved.
+func (a *API) handleCompareOffers(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	requestID, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req, err := a.Store.GetRequest(r.Context(), requestID)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	if req.BuyerID == nil || *req.BuyerID != user.ID {
+		httputil.Error(w, http.StatusForbidden, "only the lot owner can compare offers")
+		return
+	}
+
+	saved, err := a.buyerScoreWeights(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	weights, err := parseScoreWeights(r, saved)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offers, err := a.Store.ListComparableOffers(r.Context(), requestID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.JSON(w, http.StatusOK, compareOffers(req, offers, weights))
+}
+
+func (a *API) handleGetScoreWeights(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	weights, err := a.buyerScoreWeights(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, weights)
+}
+
+type scoreWeightsPayload struct {
+	Price      *float64 `json:"price"`
+	Delivery   *float64 `json:"delivery"`
+	Rating     *float64 `json:"rating"`
+	Experience *float64 `json:"experience"`
+}
+
+// handleUpdateScoreWeights saves the user's comparison weights; criteria left
+// out keep their current weight.
+func (a *API) handleUpdateScoreWeights(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	var payload scoreWeightsPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	weights, err := a.buyerScoreWeights(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, field := range []struct {
+		name   string
+		value  *float64
+		target *float64
+	}{
+		{"price", payload.Price, &weights.Price},
+		{"delivery", payload.Delivery, &weights.Delivery},
+		{"rating", payload.Rating, &weights.Rating},
+		{"experience", payload.Experience, &weights.Experience},
+	} {
+		if field.value == nil {
+			continue
+		}
+		if !validScoreWeight(*field.value) {
+			httputil.Error(w, http.StatusBadRequest, field.name+" must be a non-negative number")
+			return
+		}
+		*field.target = *field.value
+	}
+	if weights.Price+weights.Delivery+weights.Rating+weights.Experience == 0 {
+		httputil.Error(w, http.StatusBadRequest, "at least one weight must be greater than zero")
+		return
+	}
+
+	if err := a.Store.SaveOfferScoreWeights(r.Context(), user.ID, weights); err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, weights)
+}
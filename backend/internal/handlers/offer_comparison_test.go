@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"lotbuy-backend/internal/models"
+)
+
+func TestParseScoreWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    models.OfferScoreWeights
+		wantErr bool
+	}{
+		{"defaults", "", defaultScoreWeights, false},
+		{"partial override", "priceWeight=80&experienceWeight=0", models.OfferScoreWeights{Price: 80, Delivery: 20, Rating: 20, Experience: 0}, false},
+		{"fractional", "ratingWeight=2.5", models.OfferScoreWeights{Price: 50, Delivery: 20, Rating: 2.5, Experience: 10}, false},
+		{"negative", "deliveryWeight=-1", models.OfferScoreWeights{}, true},
+		{"not a number", "priceWeight=cheap", models.OfferScoreWeights{}, true},
+		{"infinite", "priceWeight=Inf", models.OfferScoreWeights{}, true},
+		{"all zero", "priceWeight=0&deliveryWeight=0&ratingWeight=0&experienceWeight=0", models.OfferScoreWeights{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/requests/1/offers/compare?"+tt.query, nil)
+			got, err := parseScoreWeights(r, defaultScoreWeights)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseScoreWeights(%q) = %+v, want error", tt.query, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseScoreWeights(%q) error = %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Fatalf("parseScoreWeights(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScoreWeightsSaved(t *testing.T) {
+	saved := models.OfferScoreWeights{Price: 10, Delivery: 70, Rating: 10, Experience: 10}
+	r := httptest.NewRequest("GET", "/api/requests/1/offers/compare?ratingWeight=30", nil)
+	got, err := parseScoreWeights(r, saved)
+	if err != nil {
+		t.Fatalf("parseScoreWeights error = %v", err)
+	}
+	want := models.OfferScoreWeights{Price: 10, Delivery: 70, Rating: 30, Experience: 10}
+	if got != want {
+		t.Fatalf("parseScoreWeights = %+v, want %+v", got, want)
+	}
+}
+
+func comparableOffer(id int64, price float64, currencyCode string, quantity int) models.ComparableOffer {
+	var o models.ComparableOffer
+	o.ID = id
+	o.PriceAmount = price
+	o.CurrencyCode = currencyCode
+	o.Quantity = quantity
+	pickup := "pickup"
+	o.ShippingMethod = &pickup
+	return o
+}
+
+func findScored(t *testing.T, comparison models.OfferComparison, id int64) models.ScoredOffer {
+	t.Helper()
+	for _, s := range comparison.Offers {
+		if s.Offer.ID == id {
+			return s
+		}
+	}
+	t.Fatalf("offer %d missing from comparison", id)
+	return models.ScoredOffer{}
+}
+
+func TestCompareOffersRanksByWeightedScore(t *testing.T) {
+	req := &models.Request{ID: 1, CurrencyCode: "USD"}
+	cheap := comparableOffer(1, 100, "USD", 1)
+	pricey := comparableOffer(2, 200, "USD", 1)
+	fast, slow := 2, 5
+	cheap.DeliveryDays = &slow
+	pricey.DeliveryDays = &fast
+
+	comparison := compareOffers(req, []models.ComparableOffer{pricey, cheap}, models.OfferScoreWeights{Price: 1})
+
+	if comparison.Offers[0].Offer.ID != 1 || comparison.Offers[0].Rank != 1 {
+		t.Fatalf("cheapest offer should rank first, got %+v", comparison.Offers[0])
+	}
+	if comparison.BestValueID == nil || *comparison.BestValueID != 1 {
+		t.Fatalf("BestValueID = %v, want 1", comparison.BestValueID)
+	}
+	if got := findScored(t, comparison, 2).Breakdown.Price; got != 50 {
+		t.Fatalf("price score of the dearer offer = %v, want 50", got)
+	}
+	// Delivery scores relative to the fastest offer: (2+1)/(5+1).
+	if got := findScored(t, comparison, 1).Breakdown.Delivery; got != 50 {
+		t.Fatalf("delivery score of the slower offer = %v, want 50", got)
+	}
+}
+
+func TestCompareOffersMissingRate(t *testing.T) {
+	req := &models.Request{ID: 1, CurrencyCode: "USD"}
+	unknown := comparableOffer(1, 10, "XYZ", 1)
+	known := comparableOffer(2, 500, "USD", 1)
+
+	comparison := compareOffers(req, []models.ComparableOffer{unknown, known}, defaultScoreWeights)
+
+	s := findScored(t, comparison, 1)
+	if s.LandedCost != nil || s.UnitLandedCost != nil {
+		t.Fatalf("offer without a rate should have no landed cost, got %v / %v", s.LandedCost, s.UnitLandedCost)
+	}
+	if s.Breakdown.Price != 0 {
+		t.Fatalf("offer without a rate should score 0 on price, got %v", s.Breakdown.Price)
+	}
+	if len(s.Warnings) == 0 {
+		t.Fatal("offer without a rate should carry a warning")
+	}
+	if comparison.BestValueID == nil || *comparison.BestValueID != 2 {
+		t.Fatalf("BestValueID = %v, want the offer with a known rate", comparison.BestValueID)
+	}
+}
+
+func TestCompareOffersZeroCost(t *testing.T) {
+	req := &models.Request{ID: 1, CurrencyCode: "USD"}
+	free := comparableOffer(1, 0, "USD", 1)
+	paid := comparableOffer(2, 50, "USD", 1)
+
+	comparison := compareOffers(req, []models.ComparableOffer{paid, free}, defaultScoreWeights)
+
+	if got := findScored(t, comparison, 1).Breakdown.Price; got != 100 {
+		t.Fatalf("free offer price score = %v, want 100", got)
+	}
+	if got := findScored(t, comparison, 2).Breakdown.Price; got != 0 {
+		t.Fatalf("paid offer price score = %v, want 0", got)
+	}
+}
+
+func TestCompareOffersWithoutDeliveryDays(t *testing.T) {
+	req := &models.Request{ID: 1, CurrencyCode: "USD"}
+	offers := []models.ComparableOffer{
+		comparableOffer(1, 100, "USD", 1),
+		comparableOffer(2, 120, "USD", 1),
+	}
+
+	comparison := compareOffers(req, offers, defaultScoreWeights)
+
+	for _, s := range comparison.Offers {
+		if s.Breakdown.Delivery != 0 {
+			t.Fatalf("offer %d delivery score = %v, want 0 when no offer states delivery days", s.Offer.ID, s.Breakdown.Delivery)
+		}
+	}
+}
+
+func TestCompareOffersNoOffers(t *testing.T) {
+	comparison := compareOffers(&models.Request{ID: 1, CurrencyCode: "USD"}, nil, defaultScoreWeights)
+	if len(comparison.Offers) != 0 || comparison.BestValueID != nil {
+		t.Fatalf("empty comparison = %+v", comparison)
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/store"
+)
+
+// answerOfferPayload optionally names the round being answered, so that an
+// answer to terms that have since changed is rejected.
+type answerOfferPayload struct {
+	Round *int `json:"round"`
+}
+
+// acceptOfferPayload is the body of an accept. KeepBackups keeps the other
+// offers on the lot in reserve instead of declining them.
+type acceptOfferPayload struct {
+	answerOfferPayload
+	KeepBackups bool `json:"keepBackups"`
+}
+
+type counterOfferPayload struct {
+	createOfferPayload
+	Round *int `json:"round"`
+}
+
+// offerParty returns the side of the negotiation the user is on. Callers have
+// already checked the user takes part in it.
+func offerParty(offer *models.Offer, user *models.User) string {
+	if offer.SellerID != nil && *offer.SellerID == user.ID {
+		return "seller"
+	}
+	return "buyer"
+}
+
+// offerCounterparty returns the user on the other side of the negotiation.
+func offerCounterparty(offer *models.Offer, req *models.Request, party string) *int64 {
+	if party == "seller" {
+		return req.BuyerID
+	}
+	return offer.SellerID
+}
+
+func writeNegotiationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrOfferUnavailable), errors.Is(err, store.ErrRequestClosed), errors.Is(err, store.ErrNotYourTurn),
+		errors.Is(err, store.ErrOfferExpired), errors.Is(err, store.ErrAuctionRunning),
+		errors.Is(err, store.ErrOffersSealed), errors.Is(err, store.ErrNotAuctionWinner):
+		httputil.Error(w, http.StatusConflict, err.Error())
+	default:
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (a *API) notifyOfferCounterparty(ctx context.Context, offer *models.Offer, req *models.Request, party, notificationType, title, body string) {
+	recipient := offerCounterparty(offer, req, party)
+	if recipient == nil {
+		return
+	}
+	// Sealed offers stay anonymous to the buyer until the deadline.
+	if party == "seller" && req.OffersSealed(time.Now()) {
+		return
+	}
+	meta, _ := json.Marshal(map[string]interface{}{
+		"offerId":   offer.ID,
+		"requestId": offer.RequestID,
+		"round":     offer.Round,
+	})
+	_, _ = a.Store.CreateNotification(ctx, store.CreateNotificationParams{
+		UserID:   *recipient,
+		Type:     notificationType,
+		Title:    title,
+		Body:     &body,
+		Metadata: meta,
+	})
+}
+
+// decodeOptionalJSON decodes the body when there is one.
+func decodeOptionalJSON(r *http.Request, v interface{}) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	return decodeJSON(r, v)
+}
+
+// handleAcceptOffer turns the latest round of an offer into a deal. The buyer
+// accepts a seller's round and the seller accepts a buyer's counter.
+func (a *API) handleAcceptOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offerID, err := parseID(r, "offerID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offer, req, allowed := a.ensureOfferAccess(w, r, offerID, user.ID)
+	if !allowed {
+		return
+	}
+	var payload acceptOfferPayload
+	if err := decodeOptionalJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	party := offerParty(offer, user)
+	if payload.KeepBackups && party != "buyer" {
+		httputil.Error(w, http.StatusBadRequest, "only the buyer can keep backup offers")
+		return
+	}
+	deal, err := a.Store.CreateDealFromOffer(r.Context(), offerID, party, payload.Round, payload.KeepBackups)
+	if err != nil {
+		writeNegotiationError(w, err)
+		return
+	}
+
+	a.notifyOfferCounterparty(r.Context(), offer, req, party, "offer.accepted", "Предложение принято",
+		"Условия по лоту "+req.Title+" приняты, сделка создана")
+	a.notifyWatchers(r.Context(), &deal.Request, "watch.closed", "Лот закрыт", "Покупатель выбрал предложение по лоту "+deal.Request.Title)
+
+	httputil.JSON(w, http.StatusCreated, deal)
+}
+
+// handleCounterOffer answers the latest round with new terms.
+func (a *API) handleCounterOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offerID, err := parseID(r, "offerID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offer, req, allowed := a.ensureOfferAccess(w, r, offerID, user.ID)
+	if !allowed {
+		return
+	}
+
+	if req.IsAuction() {
+		httputil.Error(w, http.StatusConflict, "auction bids cannot be countered")
+		return
+	}
+
+	var payload counterOfferPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if payload.CurrencyCode == "" {
+		payload.CurrencyCode = offer.CurrencyCode
+	}
+	if err := payload.validate(); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	quantity, total, err := payload.pricing(req)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	party := offerParty(offer, user)
+	validUntil, _ := payload.validUntil()
+	if validUntil != nil && party != "seller" {
+		httputil.Error(w, http.StatusBadRequest, "only the seller can set validUntil")
+		return
+	}
+	updated, round, err := a.Store.CounterOffer(r.Context(), store.CounterOfferParams{
+		OfferID:      offerID,
+		Party:        party,
+		UserID:       user.ID,
+		PriceAmount:  total,
+		CurrencyCode: payload.CurrencyCode,
+		Quantity:     quantity,
+		UnitPrice:    payload.UnitPrice,
+		Message:      payload.Message,
+		Round:        payload.Round,
+		ValidUntil:   validUntil,
+		Terms:        payload.offerTermsPayload.merge(offer.OfferTerms).terms(),
+	})
+	if err != nil {
+		writeNegotiationError(w, err)
+		return
+	}
+
+	a.notifyOfferCounterparty(r.Context(), updated, req, party, "offer.countered", "Встречное предложение",
+		"Новые условия по лоту "+req.Title+": "+formatAmount(round.PriceAmount, round.CurrencyCode))
+
+	httputil.JSON(w, http.StatusCreated, round)
+}
+
+// handleDeclineOffer lets the seller end the negotiation instead of answering
+// the buyer's counter.
+func (a *API) handleDeclineOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offerID, err := parseID(r, "offerID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offer, req, allowed := a.ensureOfferAccess(w, r, offerID, user.ID)
+	if !allowed {
+		return
+	}
+	if offerParty(offer, user) != "seller" {
+		httputil.Error(w, http.StatusForbidden, "only the seller can decline a counter-offer")
+		return
+	}
+	var payload answerOfferPayload
+	if err := decodeOptionalJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := a.Store.DeclineOffer(r.Context(), offerID, payload.Round)
+	if err != nil {
+		writeNegotiationError(w, err)
+		return
+	}
+
+	a.notifyOfferCounterparty(r.Context(), updated, req, "seller", "offer.declined", "Предложение отклонено",
+		"Продавец отклонил встречное предложение по лоту "+req.Title+" (раунд "+strconv.Itoa(updated.Round)+")")
+
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+func (a *API) handleListOfferRounds(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offerID, err := parseID(r, "offerID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, _, allowed := a.ensureOfferAccess(w, r, offerID, user.ID); !allowed {
+		return
+	}
+
+	rounds, err := a.Store.ListOfferRounds(r.Context(), offerID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, rounds)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"lotbuy-backend/internal/currency"
+	"lotbuy-backend/internal/models"
+)
+
+// offerVisibilities are the policies for showing the offers on a lot to
+// anyone but its buyer.
+var offerVisibilities = map[string]bool{"public": true, "summary": true, "private": true}
+
+// activeOfferStatuses are the offers still in play, which summaries count.
+var activeOfferStatuses = map[string]bool{"pending": true, "backup": true, "accepted": true}
+
+// validateOfferVisibility checks the policy suits a lot in mode: auction bids
+// are anonymous and sealed offers hidden, so neither lot can show its offers
+// publicly.
+func validateOfferVisibility(visibility, mode string) error {
+	if !offerVisibilities[visibility] {
+		return errors.New("offerVisibility must be one of public, summary, private")
+	}
+	if visibility == "public" && mode != "" && mode != "standard" {
+		return errors.New("offerVisibility public is only available on standard lots")
+	}
+	return nil
+}
+
+// buildOfferListing applies the lot's offer visibility policy to the offers
+// the store let the caller see:
+//   - the buyer sees every offer in full, and on public lots everyone does;
+//   - on summary lots sellers see their own offers with the count and price
+//     range of the active ones, and everyone else only the count;
+//   - on private lots sellers see their own offers and nothing more.
+//
+// Nothing is summarized while a sealed-bid lot is sealed.
+func buildOfferListing(req *models.Request, user *models.User, offers []models.Offer) models.OfferListing {
+	listing := models.OfferListing{
+		RequestID:       req.ID,
+		View:            "guest",
+		OfferVisibility: req.OfferVisibility,
+		Offers:          []models.Offer{},
+	}
+	own := []models.Offer{}
+	for _, o := range offers {
+		if user != nil && o.SellerID != nil && *o.SellerID == user.ID {
+			own = append(own, o)
+		}
+	}
+	switch {
+	case isRequestOwner(req, user):
+		listing.View = "buyer"
+	case len(own) > 0:
+		listing.View = "seller"
+	}
+
+	showAll := listing.View == "buyer" || req.OfferVisibility == "public"
+	if showAll {
+		listing.Offers = append(listing.Offers, offers...)
+	} else {
+		listing.Offers = own
+	}
+	if req.OffersSealed(time.Now()) || (!showAll && req.OfferVisibility == "private") {
+		return listing
+	}
+
+	count := 0
+	var priceRange *models.OfferPriceRange
+	for _, o := range offers {
+		if !activeOfferStatuses[o.Status] {
+			continue
+		}
+		count++
+		if o.Quantity <= 0 {
+			continue
+		}
+		unit, ok := currency.Convert(o.PriceAmount/float64(o.Quantity), o.CurrencyCode, req.CurrencyCode)
+		if !ok {
+			continue
+		}
+		unit = roundCents(unit)
+		if priceRange == nil {
+			priceRange = &models.OfferPriceRange{MinUnitPrice: unit, MaxUnitPrice: unit, CurrencyCode: req.CurrencyCode}
+		}
+		if unit < priceRange.MinUnitPrice {
+			priceRange.MinUnitPrice = unit
+		}
+		if unit > priceRange.MaxUnitPrice {
+			priceRange.MaxUnitPrice = unit
+		}
+	}
+	listing.Count = &count
+	if listing.View != "guest" || req.OfferVisibility == "public" {
+		listing.PriceRange = priceRange
+	}
+	return listing
+}
+
+// applyAuctionVisibility trims the auction state to what the lot's offer
+// visibility policy lets the caller see, as buildOfferListing does for the
+// offers themselves:
+//   - the buyer sees everything;
+//   - on summary lots signed-in sellers see the bid count and prices, and
+//     everyone else only the count;
+//   - on private lots sellers see their own bid and nothing more.
+//
+// Only the buyer and the winner learn which offer won.
+func applyAuctionVisibility(req *models.Request, user *models.User, state *models.AuctionState) {
+	if isRequestOwner(req, user) {
+		return
+	}
+	if state.YourOfferID == nil || state.WinnerOfferID == nil || *state.YourOfferID != *state.WinnerOfferID {
+		state.WinnerOfferID = nil
+	}
+
+	showPrices := req.OfferVisibility == "public"
+	if req.OfferVisibility == "summary" {
+		showPrices = user != nil && (user.Role == "seller" || state.YourOfferID != nil)
+	}
+	if !showPrices {
+		state.LowestBid = nil
+		state.MaxNextBid = nil
+	}
+	if req.OfferVisibility == "private" {
+		state.BidCount = nil
+	}
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
 	"lotbuy-backend/internal/httputil"
 	"lotbuy-backend/internal/models"
@@ -12,19 +16,130 @@ import (
 )
 
 type createOfferPayload struct {
-	PriceAmount  float64 `json:"priceAmount"`
-	CurrencyCode string  `json:"currencyCode"`
-	Message      *string `json:"message"`
+	PriceAmount  float64  `json:"priceAmount"`
+	CurrencyCode string   `json:"currencyCode"`
+	Message      *string  `json:"message"`
+	UnitPrice    *float64 `json:"unitPrice"`
+	Quantity     *int     `json:"quantity"`
+	ValidUntil   *string  `json:"validUntil"`
+	offerTermsPayload
+}
+
+// offerTermsPayload carries the optional structured terms of an offer.
+type offerTermsPayload struct {
+	DeliveryDays   *int     `json:"deliveryDays"`
+	ShippingCost   *float64 `json:"shippingCost"`
+	ShippingMethod *string  `json:"shippingMethod"`
+	WarrantyMonths *int     `json:"warrantyMonths"`
+	PaymentTerms   *string  `json:"paymentTerms"`
+	ItemCondition  *string  `json:"itemCondition"`
+}
+
+var shippingMethods = map[string]bool{"pickup": true, "courier": true, "post": true, "freight": true}
+
+var paymentTerms = map[string]bool{"prepayment": true, "on-delivery": true, "escrow": true, "deferred": true}
+
+func (p offerTermsPayload) validate() error {
+	if p.DeliveryDays != nil && (*p.DeliveryDays < 0 || *p.DeliveryDays > 365) {
+		return errors.New("deliveryDays must be between 0 and 365")
+	}
+	if p.ShippingCost != nil && *p.ShippingCost < 0 {
+		return errors.New("shippingCost cannot be negative")
+	}
+	if p.ShippingMethod != nil && !shippingMethods[strings.ToLower(strings.TrimSpace(*p.ShippingMethod))] {
+		return errors.New("shippingMethod must be one of pickup, courier, post, freight")
+	}
+	if p.WarrantyMonths != nil && (*p.WarrantyMonths < 0 || *p.WarrantyMonths > 120) {
+		return errors.New("warrantyMonths must be between 0 and 120")
+	}
+	if p.PaymentTerms != nil && !paymentTerms[strings.ToLower(strings.TrimSpace(*p.PaymentTerms))] {
+		return errors.New("paymentTerms must be one of prepayment, on-delivery, escrow, deferred")
+	}
+	if p.ItemCondition != nil {
+		condition := strings.ToLower(strings.TrimSpace(*p.ItemCondition))
+		if condition == "any" || !itemConditions[condition] {
+			return errors.New("itemCondition must be one of new, like-new, good, fair")
+		}
+	}
+	return nil
+}
+
+// terms normalizes the validated terms for storage.
+func (p offerTermsPayload) terms() models.OfferTerms {
+	return models.OfferTerms{
+		DeliveryDays:   p.DeliveryDays,
+		ShippingCost:   p.ShippingCost,
+		ShippingMethod: lowerOrNil(p.ShippingMethod),
+		WarrantyMonths: p.WarrantyMonths,
+		PaymentTerms:   lowerOrNil(p.PaymentTerms),
+		ItemCondition:  lowerOrNil(p.ItemCondition),
+	}
+}
+
+func lowerOrNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	lowered := strings.ToLower(strings.TrimSpace(*value))
+	return &lowered
 }
 
 func (p createOfferPayload) validate() error {
 	if p.CurrencyCode == "" {
 		return errors.New("currencyCode is required")
 	}
-	if p.PriceAmount <= 0 {
+	if p.UnitPrice != nil {
+		if *p.UnitPrice <= 0 {
+			return errors.New("unitPrice must be greater than zero")
+		}
+	} else if p.PriceAmount <= 0 {
 		return errors.New("priceAmount must be greater than zero")
 	}
-	return nil
+	if p.Quantity != nil && *p.Quantity <= 0 {
+		return errors.New("quantity must be greater than zero")
+	}
+	if _, err := p.validUntil(); err != nil {
+		return err
+	}
+	return p.offerTermsPayload.validate()
+}
+
+// validUntil parses the optional end of the offer's validity, which must lie
+// in the future.
+func (p createOfferPayload) validUntil() (*time.Time, error) {
+	return parseValidUntil(p.ValidUntil)
+}
+
+func parseValidUntil(value *string) (*time.Time, error) {
+	until, err := parseOptionalTime(value)
+	if err != nil {
+		return nil, errors.New("validUntil must be an RFC3339 string")
+	}
+	if until != nil && !until.After(time.Now()) {
+		return nil, errors.New("validUntil must be in the future")
+	}
+	return until, nil
+}
+
+// pricing resolves the quoted quantity and total price against the lot. A
+// seller may offer part of the requested quantity, and may quote per unit, in
+// which case the total is derived from the unit price.
+func (p createOfferPayload) pricing(req *models.Request) (quantity int, total float64, err error) {
+	quantity = req.Quantity
+	if p.Quantity != nil {
+		quantity = *p.Quantity
+	}
+	if quantity > req.Quantity {
+		return 0, 0, fmt.Errorf("quantity cannot exceed the requested %d %s", req.Quantity, req.Unit)
+	}
+	if p.UnitPrice == nil {
+		return quantity, p.PriceAmount, nil
+	}
+	total = math.Round(*p.UnitPrice*float64(quantity)*100) / 100
+	if p.PriceAmount > 0 && math.Abs(p.PriceAmount-total) >= 0.01 {
+		return 0, 0, errors.New("priceAmount must equal unitPrice multiplied by quantity")
+	}
+	return quantity, total, nil
 }
 
 func (a *API) handleCreateOffer(w http.ResponseWriter, r *http.Request) {
@@ -54,10 +169,45 @@ func (a *API) handleCreateOffer(w http.ResponseWriter, r *http.Request) {
 		httputil.Error(w, http.StatusNotFound, "request not found")
 		return
 	}
+	if !a.canViewRequest(r, req, user) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
 	if req.BuyerID != nil && *req.BuyerID == user.ID {
 		httputil.Error(w, http.StatusForbidden, "request owners cannot create offers on their own lot")
 		return
 	}
+	if req.Status != "open" {
+		httputil.Error(w, http.StatusConflict, "the lot is not accepting offers")
+		return
+	}
+
+	// A seller has one active offer per lot: offering again revises it as a
+	// new round, or answers the buyer's counter when it awaits the seller.
+	existing, err := a.Store.GetActiveOffer(r.Context(), requestID, user.ID)
+	switch {
+	case err == nil && existing.Awaiting == "seller":
+		a.counterWithOffer(w, r, user, existing, req, payload)
+		return
+	case err == nil:
+		a.reviseOffer(w, r, user, existing, req, payload)
+		return
+	case !errors.Is(err, sql.ErrNoRows):
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	quantity, total, err := payload.pricing(req)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkAuctionBidTerms(req, payload.CurrencyCode, quantity); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	validUntil, _ := payload.validUntil()
+	leader := a.auctionLeader(r.Context(), req)
 
 	sellerName := user.FullName
 	if sellerName == "" {
@@ -70,18 +220,27 @@ func (a *API) handleCreateOffer(w http.ResponseWriter, r *http.Request) {
 		SellerName:   sellerName,
 		SellerAvatar: user.AvatarURL,
 		SellerRating: nil,
-		PriceAmount:  payload.PriceAmount,
+		PriceAmount:  total,
 		CurrencyCode: payload.CurrencyCode,
 		Message:      payload.Message,
+		Quantity:     quantity,
+		UnitPrice:    payload.UnitPrice,
+		ValidUntil:   validUntil,
+		Terms:        payload.terms(),
 	})
 	if err != nil {
-		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		a.writeAuctionBidError(w, r, req, err)
 		return
 	}
+	a.notifyOutbid(r.Context(), req, leader, offer)
 
 	if req.BuyerID != nil {
 		title := "Новое предложения для лота " + req.Title
 		body := sellerName + " отправил предложение"
+		if req.IsSealed() {
+			// The buyer must not learn who bid before the deadline.
+			body = "Получено запечатанное предложение; оно будет доступно после окончания приёма предложений"
+		}
 		meta, _ := json.Marshal(map[string]interface{}{
 			"offerId":   offer.ID,
 			"requestId": requestID,
@@ -105,36 +264,370 @@ func (a *API) handleListOffers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	offers, err := a.Store.ListOffersByRequest(r.Context(), requestID)
+	user := a.optionalUser(r)
+	req, err := a.Store.GetRequest(r.Context(), requestID)
+	if err != nil || !a.canViewRequest(r, req, user) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+
+	var viewerID *int64
+	if user != nil {
+		viewerID = &user.ID
+	}
+	offers, err := a.Store.ListOffersByRequest(r.Context(), requestID, viewerID)
 	if err != nil {
 		httputil.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	httputil.JSON(w, http.StatusOK, offers)
+	httputil.JSON(w, http.StatusOK, buildOfferListing(req, user, offers))
+}
+
+// updateOfferPayload revises a pending offer. Omitted fields keep their
+// current values; an empty message clears it.
+type updateOfferPayload struct {
+	PriceAmount  *float64 `json:"priceAmount"`
+	CurrencyCode *string  `json:"currencyCode"`
+	Message      *string  `json:"message"`
+	UnitPrice    *float64 `json:"unitPrice"`
+	Quantity     *int     `json:"quantity"`
+	ValidUntil   *string  `json:"validUntil"`
+	offerTermsPayload
+}
+
+// merge applies the changes to the offer's current terms. A new total
+// price replaces per-unit pricing; a new quantity alone is re-priced at the
+// current unit price.
+func (p updateOfferPayload) merge(offer *models.Offer) createOfferPayload {
+	merged := createOfferPayload{
+		CurrencyCode: offer.CurrencyCode,
+		Message:      offer.Message,
+		Quantity:     &offer.Quantity,
+	}
+	if p.CurrencyCode != nil {
+		merged.CurrencyCode = strings.TrimSpace(*p.CurrencyCode)
+	}
+	if p.Message != nil {
+		merged.Message = trimmedOrNil(p.Message)
+	}
+	if p.Quantity != nil {
+		merged.Quantity = p.Quantity
+	}
+	merged.ValidUntil = p.ValidUntil
+	merged.offerTermsPayload = p.offerTermsPayload.merge(offer.OfferTerms)
+	switch {
+	case p.UnitPrice != nil:
+		merged.UnitPrice = p.UnitPrice
+		if p.PriceAmount != nil {
+			merged.PriceAmount = *p.PriceAmount
+		}
+	case p.PriceAmount != nil:
+		merged.PriceAmount = *p.PriceAmount
+	case offer.UnitPrice != nil:
+		merged.UnitPrice = offer.UnitPrice
+	default:
+		merged.PriceAmount = offer.PriceAmount
+	}
+	return merged
+}
+
+// merge fills the terms left out of an update from the offer's current ones.
+func (p offerTermsPayload) merge(current models.OfferTerms) offerTermsPayload {
+	if p.DeliveryDays == nil {
+		p.DeliveryDays = current.DeliveryDays
+	}
+	if p.ShippingCost == nil {
+		p.ShippingCost = current.ShippingCost
+	}
+	if p.ShippingMethod == nil {
+		p.ShippingMethod = current.ShippingMethod
+	}
+	if p.WarrantyMonths == nil {
+		p.WarrantyMonths = current.WarrantyMonths
+	}
+	if p.PaymentTerms == nil {
+		p.PaymentTerms = current.PaymentTerms
+	}
+	if p.ItemCondition == nil {
+		p.ItemCondition = current.ItemCondition
+	}
+	return p
+}
+
+type rejectOfferPayload struct {
+	Reason *string `json:"reason"`
 }
 
-func (a *API) handleAcceptOffer(w http.ResponseWriter, r *http.Request) {
+func writeOfferTransitionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrOfferUnavailable):
+		httputil.Error(w, http.StatusConflict, "offer is no longer pending")
+	case errors.Is(err, store.ErrNotYourTurn):
+		httputil.Error(w, http.StatusConflict, "the buyer has countered; answer with a counter-offer instead")
+	case errors.Is(err, store.ErrRequestClosed):
+		httputil.Error(w, http.StatusConflict, "the lot is not accepting offers")
+	case errors.Is(err, store.ErrActiveOfferExists):
+		httputil.Error(w, http.StatusConflict, "you already have an active offer on this lot; revise it instead")
+	default:
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// loadSellerOffer returns the offer in the route if the user made it.
+func (a *API) loadSellerOffer(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Offer, *models.Request, bool) {
 	offerID, err := parseID(r, "offerID")
 	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	offer, req, allowed := a.ensureOfferAccess(w, r, offerID, user.ID)
+	if !allowed {
+		return nil, nil, false
+	}
+	if offer.SellerID == nil || *offer.SellerID != user.ID {
+		httputil.Error(w, http.StatusForbidden, "only the seller can change this offer")
+		return nil, nil, false
+	}
+	return offer, req, true
+}
+
+func (a *API) handleUpdateOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offer, req, ok := a.loadSellerOffer(w, r, user)
+	if !ok {
+		return
+	}
+
+	var payload updateOfferPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if payload == (updateOfferPayload{}) {
+		httputil.Error(w, http.StatusBadRequest, "no fields provided for update")
+		return
+	}
+	merged := payload.merge(offer)
+	if err := merged.validate(); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.reviseOffer(w, r, user, offer, req, merged)
+}
+
+// reviseOffer replaces the terms of the seller's offer with terms, recorded
+// as a new round, and tells the buyer.
+func (a *API) reviseOffer(w http.ResponseWriter, r *http.Request, user *models.User, offer *models.Offer, req *models.Request, terms createOfferPayload) {
+	quantity, total, err := terms.pricing(req)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkAuctionBidTerms(req, terms.CurrencyCode, quantity); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	validUntil, _ := terms.validUntil()
+	leader := a.auctionLeader(r.Context(), req)
+
+	updated, err := a.Store.EditOffer(r.Context(), store.EditOfferParams{
+		OfferID:      offer.ID,
+		SellerID:     user.ID,
+		PriceAmount:  total,
+		CurrencyCode: terms.CurrencyCode,
+		Quantity:     quantity,
+		UnitPrice:    terms.UnitPrice,
+		Message:      terms.Message,
+		ValidUntil:   validUntil,
+		Terms:        terms.terms(),
+	})
+	if err != nil {
+		a.writeAuctionBidError(w, r, req, err)
+		return
+	}
+	a.notifyOutbid(r.Context(), req, leader, updated)
+
+	a.notifyOfferCounterparty(r.Context(), updated, req, "seller", "offer.updated", "Предложение изменено",
+		updated.SellerName+" изменил предложение по лоту "+req.Title+": "+formatAmount(updated.PriceAmount, updated.CurrencyCode))
+
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+// repeatOfferCounter turns a repeat offer, made while the seller's offer
+// awaits the seller, into the seller's counter to the buyer's latest round.
+// The terms replace the offer's as a revision would.
+func repeatOfferCounter(existing *models.Offer, req *models.Request, sellerID int64, terms createOfferPayload) (store.CounterOfferParams, error) {
+	quantity, total, err := terms.pricing(req)
+	if err != nil {
+		return store.CounterOfferParams{}, err
+	}
+	validUntil, _ := terms.validUntil()
+	round := existing.Round
+	return store.CounterOfferParams{
+		OfferID:      existing.ID,
+		Party:        "seller",
+		UserID:       sellerID,
+		PriceAmount:  total,
+		CurrencyCode: terms.CurrencyCode,
+		Quantity:     quantity,
+		UnitPrice:    terms.UnitPrice,
+		Message:      terms.Message,
+		Round:        &round,
+		ValidUntil:   validUntil,
+		Terms:        terms.terms(),
+	}, nil
+}
+
+// counterWithOffer records a repeat offer on a negotiation awaiting the
+// seller as their counter round, and tells the buyer.
+func (a *API) counterWithOffer(w http.ResponseWriter, r *http.Request, user *models.User, offer *models.Offer, req *models.Request, terms createOfferPayload) {
+	params, err := repeatOfferCounter(offer, req, user.ID, terms)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	updated, round, err := a.Store.CounterOffer(r.Context(), params)
+	if err != nil {
+		writeNegotiationError(w, err)
+		return
+	}
+
+	a.notifyOfferCounterparty(r.Context(), updated, req, "seller", "offer.countered", "Встречное предложение",
+		"Новые условия по лоту "+req.Title+": "+formatAmount(round.PriceAmount, round.CurrencyCode))
+
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+// extendOfferPayload moves the validity of an offer, either to validUntil or
+// by days from now.
+type extendOfferPayload struct {
+	ValidUntil *string `json:"validUntil"`
+	Days       *int    `json:"days"`
+}
+
+// handleExtendOffer lets the seller prolong an offer, reviving it if it has
+// already expired.
+func (a *API) handleExtendOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offer, req, ok := a.loadSellerOffer(w, r, user)
+	if !ok {
+		return
+	}
+
+	var payload extendOfferPayload
+	if err := decodeJSON(r, &payload); err != nil {
 		httputil.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	var until *time.Time
+	switch {
+	case payload.ValidUntil != nil && payload.Days != nil:
+		httputil.Error(w, http.StatusBadRequest, "provide either validUntil or days")
+		return
+	case payload.Days != nil:
+		if *payload.Days <= 0 || *payload.Days > 365 {
+			httputil.Error(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		ts := time.Now().AddDate(0, 0, *payload.Days)
+		until = &ts
+	default:
+		parsed, err := parseValidUntil(payload.ValidUntil)
+		if err != nil {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		until = parsed
+	}
+	if until == nil {
+		httputil.Error(w, http.StatusBadRequest, "validUntil or days is required")
+		return
+	}
 
-	deal, err := a.Store.CreateDealFromOffer(r.Context(), offerID)
+	updated, err := a.Store.ExtendOffer(r.Context(), offer.ID, *until)
 	if err != nil {
-		status := http.StatusInternalServerError
-		switch err {
-		case store.ErrOfferUnavailable:
-			status = http.StatusConflict
-		case store.ErrRequestClosed:
-			status = http.StatusConflict
+		switch {
+		case errors.Is(err, store.ErrRequestClosed):
+			httputil.Error(w, http.StatusConflict, "the lot is no longer open")
+		default:
+			writeOfferTransitionError(w, err)
 		}
-		httputil.Error(w, status, err.Error())
 		return
 	}
 
-	httputil.JSON(w, http.StatusCreated, deal)
+	a.notifyOfferCounterparty(r.Context(), updated, req, "seller", "offer.extended", "Предложение продлено",
+		updated.SellerName+" продлил предложение по лоту "+req.Title+" до "+until.UTC().Format("02.01.2006 15:04")+" UTC")
+
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+func (a *API) handleWithdrawOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offer, req, ok := a.loadSellerOffer(w, r, user)
+	if !ok {
+		return
+	}
+
+	updated, err := a.Store.WithdrawOffer(r.Context(), offer.ID)
+	if err != nil {
+		writeOfferTransitionError(w, err)
+		return
+	}
+
+	a.notifyOfferCounterparty(r.Context(), updated, req, "seller", "offer.withdrawn", "Предложение отозвано",
+		updated.SellerName+" отозвал предложение по лоту "+req.Title)
+
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+func (a *API) handleRejectOffer(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	offerID, err := parseID(r, "offerID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offer, req, allowed := a.ensureOfferAccess(w, r, offerID, user.ID)
+	if !allowed {
+		return
+	}
+	if !isRequestOwner(req, user) {
+		httputil.Error(w, http.StatusForbidden, "only the buyer can reject an offer")
+		return
+	}
+
+	var payload rejectOfferPayload
+	if err := decodeOptionalJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := a.Store.RejectOffer(r.Context(), offer.ID, trimmedOrNil(payload.Reason))
+	if err != nil {
+		writeOfferTransitionError(w, err)
+		return
+	}
+
+	body := "Покупатель отклонил ваше предложение по лоту " + req.Title
+	if updated.RejectionReason != nil {
+		body += ": " + *updated.RejectionReason
+	}
+	a.notifyOfferCounterparty(r.Context(), updated, req, "buyer", "offer.rejected", "Предложение отклонено", body)
+
+	httputil.JSON(w, http.StatusOK, updated)
 }
 
 type messagePayload struct {
@@ -143,7 +636,7 @@ type messagePayload struct {
 }
 
 func (a *API) ensureOfferAccess(w http.ResponseWriter, r *http.Request, offerID int64, userID int64) (*models.Offer, *models.Request, bool) {
-	offer, err := a.Store.GetOffer(r.Context(), offerID)
+	offer, err := a.Store.GetVisibleOffer(r.Context(), offerID, userID)
 	if err != nil {
 		httputil.Error(w, http.StatusNotFound, "offer not found")
 		return nil, nil, false
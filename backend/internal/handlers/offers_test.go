@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"testing"
+
+	"lotbuy-backend/internal/models"
+)
+
+func TestRepeatOfferCounter(t *testing.T) {
+	req := &models.Request{ID: 1, Quantity: 10, Unit: "pcs", CurrencyCode: "EUR"}
+	existing := &models.Offer{ID: 7, RequestID: 1, Round: 2, Awaiting: "seller", Quantity: 10}
+	unitPrice := 12.5
+	days := 5
+	message := "best I can do"
+	payload := createOfferPayload{
+		CurrencyCode: "eur",
+		UnitPrice:    &unitPrice,
+		Message:      &message,
+		Quantity:     &days,
+		offerTermsPayload: offerTermsPayload{
+			DeliveryDays: &days,
+		},
+	}
+
+	params, err := repeatOfferCounter(existing, req, 42, payload)
+	if err != nil {
+		t.Fatalf("repeatOfferCounter error = %v", err)
+	}
+	if params.OfferID != 7 || params.Party != "seller" || params.UserID != 42 {
+		t.Fatalf("params = %+v, want a seller counter on offer 7 by user 42", params)
+	}
+	if params.Round == nil || *params.Round != 2 {
+		t.Fatalf("Round = %v, want the buyer's round 2", params.Round)
+	}
+	if params.Quantity != 5 || params.PriceAmount != 62.5 {
+		t.Fatalf("Quantity, PriceAmount = %d, %v, want 5, 62.5", params.Quantity, params.PriceAmount)
+	}
+	if params.Message == nil || *params.Message != message {
+		t.Fatalf("Message = %v, want %q", params.Message, message)
+	}
+	if params.Terms.DeliveryDays == nil || *params.Terms.DeliveryDays != 5 {
+		t.Fatalf("Terms = %+v, want deliveryDays 5", params.Terms)
+	}
+
+	tooMany := 11
+	payload.Quantity = &tooMany
+	if _, err := repeatOfferCounter(existing, req, 42, payload); err == nil {
+		t.Fatal("repeatOfferCounter accepted more than the requested quantity")
+	}
+}
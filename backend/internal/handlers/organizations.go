@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+)
+
+type createOrganizationPayload struct {
+	Name string `json:"name"`
+}
+
+type addOrganizationMemberPayload struct {
+	Email string `json:"email"`
+}
+
+func (a *API) handleListOrganizations(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	orgs, err := a.Store.ListUserOrganizations(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, orgs)
+}
+
+func (a *API) handleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var payload createOrganizationPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		httputil.Error(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	org, err := a.Store.CreateOrganization(r.Context(), name, user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, org)
+}
+
+// loadOrganizationRole checks that the user belongs to the organization in
+// the route and, when ownerOnly is set, that they own it.
+func (a *API) loadOrganizationRole(w http.ResponseWriter, r *http.Request, user *models.User, ownerOnly bool) (int64, bool) {
+	orgID, err := parseID(r, "organizationID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+	role, err := a.Store.GetOrganizationRole(r.Context(), orgID, user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return 0, false
+	}
+	if role == "" {
+		httputil.Error(w, http.StatusNotFound, "organization not found")
+		return 0, false
+	}
+	if ownerOnly && role != "owner" {
+		httputil.Error(w, http.StatusForbidden, "only organization owners can manage members")
+		return 0, false
+	}
+	return orgID, true
+}
+
+func (a *API) handleListOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	orgID, ok := a.loadOrganizationRole(w, r, user, false)
+	if !ok {
+		return
+	}
+
+	members, err := a.Store.ListOrganizationMembers(r.Context(), orgID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, members)
+}
+
+func (a *API) handleAddOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	orgID, ok := a.loadOrganizationRole(w, r, user, true)
+	if !ok {
+		return
+	}
+
+	var payload addOrganizationMemberPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	email := strings.TrimSpace(payload.Email)
+	if email == "" {
+		httputil.Error(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	member, err := a.Store.AddOrganizationMember(r.Context(), orgID, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "no user with this email")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, member)
+}
+
+func (a *API) handleRemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := parseID(r, "userID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Members may leave on their own; removing others is up to owners.
+	orgID, ok := a.loadOrganizationRole(w, r, user, memberID != user.ID)
+	if !ok {
+		return
+	}
+	role, err := a.Store.GetOrganizationRole(r.Context(), orgID, memberID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if role == "owner" {
+		httputil.Error(w, http.StatusConflict, "organization owners cannot be removed")
+		return
+	}
+
+	if err := a.Store.RemoveOrganizationMember(r.Context(), orgID, memberID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "member not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
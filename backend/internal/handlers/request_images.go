@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/store"
+)
+
+type addRequestImagePayload struct {
+	URL     string  `json:"url"`
+	AltText *string `json:"altText"`
+	Width   *int    `json:"width"`
+	Height  *int    `json:"height"`
+	IsCover bool    `json:"isCover"`
+}
+
+func (p addRequestImagePayload) validate() error {
+	if strings.TrimSpace(p.URL) == "" {
+		return errors.New("url is required")
+	}
+	if p.Width != nil && *p.Width <= 0 {
+		return errors.New("width must be greater than zero")
+	}
+	if p.Height != nil && *p.Height <= 0 {
+		return errors.New("height must be greater than zero")
+	}
+	return nil
+}
+
+type updateRequestImagePayload struct {
+	AltText *json.RawMessage `json:"altText"`
+	IsCover *bool            `json:"isCover"`
+}
+
+type reorderRequestImagesPayload struct {
+	ImageIDs []int64 `json:"imageIds"`
+}
+
+// loadOwnedRequest fetches the request named in the route and verifies that
+// the user is its buyer.
+func (a *API) loadOwnedRequest(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Request, bool) {
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	req, err := a.Store.GetRequest(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return nil, false
+	}
+	if !isRequestOwner(req, user) {
+		httputil.Error(w, http.StatusForbidden, "you do not have permission to modify this request")
+		return nil, false
+	}
+	return req, true
+}
+
+func (a *API) handleListRequestImages(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req, err := a.Store.GetRequest(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	if !a.canViewRequest(r, req, a.optionalUser(r)) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+
+	images, err := a.Store.ListRequestImages(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, images)
+}
+
+func (a *API) handleAddRequestImage(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	var payload addRequestImagePayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := payload.validate(); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var altText *string
+	if payload.AltText != nil {
+		if trimmed := strings.TrimSpace(*payload.AltText); trimmed != "" {
+			altText = &trimmed
+		}
+	}
+
+	img, err := a.Store.AddRequestImage(r.Context(), store.AddRequestImageParams{
+		RequestID: req.ID,
+		URL:       strings.TrimSpace(payload.URL),
+		AltText:   altText,
+		Width:     payload.Width,
+		Height:    payload.Height,
+		IsCover:   payload.IsCover,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrTooManyImages) {
+			httputil.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, img)
+}
+
+func (a *API) handleUpdateRequestImage(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+	imageID, err := parseID(r, "imageID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var payload updateRequestImagePayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if payload.AltText == nil && payload.IsCover == nil {
+		httputil.Error(w, http.StatusBadRequest, "no fields provided for update")
+		return
+	}
+
+	params := store.UpdateRequestImageParams{RequestID: req.ID, ImageID: imageID, IsCover: payload.IsCover}
+	if payload.AltText != nil {
+		if *payload.AltText == nil {
+			params.AltText = &sql.NullString{Valid: false}
+		} else {
+			var value string
+			if err := json.Unmarshal(*payload.AltText, &value); err != nil {
+				httputil.Error(w, http.StatusBadRequest, "altText must be a string or null")
+				return
+			}
+			value = strings.TrimSpace(value)
+			params.AltText = &sql.NullString{String: value, Valid: value != ""}
+		}
+	}
+
+	img, err := a.Store.UpdateRequestImage(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "image not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, img)
+}
+
+func (a *API) handleReorderRequestImages(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	var payload reorderRequestImagesPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	images, err := a.Store.ReorderRequestImages(r.Context(), req.ID, payload.ImageIDs)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidImageOrder) {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, images)
+}
+
+func (a *API) handleDeleteRequestImage(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+	imageID, err := parseID(r, "imageID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.Store.DeleteRequestImage(r.Context(), req.ID, imageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "image not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
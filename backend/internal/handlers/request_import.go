@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/sheet"
+	"lotbuy-backend/internal/store"
+)
+
+const (
+	maxImportFileSize = 5 << 20
+	maxImportRows     = 500
+)
+
+// importColumns maps normalized header names to setters filling the matching
+// createRequestPayload field from a cell. Headers use the JSON field names of
+// POST /api/requests.
+var importColumns = map[string]func(p *createRequestPayload, value string) error{
+	"title":           func(p *createRequestPayload, v string) error { p.Title = v; return nil },
+	"description":     func(p *createRequestPayload, v string) error { p.Description = &v; return nil },
+	"budgetamount":    func(p *createRequestPayload, v string) error { return parseCellFloat(v, &p.BudgetAmount) },
+	"currencycode":    func(p *createRequestPayload, v string) error { p.CurrencyCode = v; return nil },
+	"imageurl":        func(p *createRequestPayload, v string) error { p.ImageURL = &v; return nil },
+	"category":        func(p *createRequestPayload, v string) error { p.Category = &v; return nil },
+	"subcategory":     func(p *createRequestPayload, v string) error { p.Subcategory = &v; return nil },
+	"locationcity":    func(p *createRequestPayload, v string) error { p.LocationCity = &v; return nil },
+	"locationregion":  func(p *createRequestPayload, v string) error { p.LocationRegion = &v; return nil },
+	"locationcountry": func(p *createRequestPayload, v string) error { p.LocationCountry = &v; return nil },
+	"deadlineat":      func(p *createRequestPayload, v string) error { return parseCellTime(v, &p.DeadlineAt) },
+	"publishat":       func(p *createRequestPayload, v string) error { return parseCellTime(v, &p.PublishAt) },
+	"condition":       func(p *createRequestPayload, v string) error { p.Condition = &v; return nil },
+	"unit":            func(p *createRequestPayload, v string) error { p.Unit = &v; return nil },
+	"visibility":      func(p *createRequestPayload, v string) error { p.Visibility = &v; return nil },
+	"offervisibility": func(p *createRequestPayload, v string) error { p.OfferVisibility = &v; return nil },
+	"quantity": func(p *createRequestPayload, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		p.Quantity = &n
+		return nil
+	},
+	"latitude": func(p *createRequestPayload, v string) error {
+		p.Latitude = new(float64)
+		return parseCellFloat(v, p.Latitude)
+	},
+	"longitude": func(p *createRequestPayload, v string) error {
+		p.Longitude = new(float64)
+		return parseCellFloat(v, p.Longitude)
+	},
+	"draft": func(p *createRequestPayload, v string) error {
+		switch strings.ToLower(v) {
+		case "true", "yes", "1", "да":
+			p.Draft = true
+		case "false", "no", "0", "нет":
+			p.Draft = false
+		default:
+			return errors.New("must be true or false")
+		}
+		return nil
+	},
+	"attributes": func(p *createRequestPayload, v string) error {
+		if !json.Valid([]byte(v)) {
+			return errors.New("must be a JSON object")
+		}
+		p.Attributes = json.RawMessage(v)
+		return nil
+	},
+}
+
+func normalizeHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(header)
+}
+
+// parseCellFloat accepts both "1500.5" and the "1 500,5" style spreadsheets
+// produce in comma-decimal locales.
+func parseCellFloat(value string, dest *float64) error {
+	value = strings.NewReplacer(" ", "", "\u00a0", "").Replace(value)
+	if !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errors.New("must be a number")
+	}
+	*dest = v
+	return nil
+}
+
+var importDateLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02", "02.01.2006 15:04", "02.01.2006"}
+
+// parseCellTime normalizes dates typed as text or stored as spreadsheet
+// serial numbers to RFC3339.
+func parseCellTime(value string, dest **string) error {
+	var ts time.Time
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		ts = sheet.SerialTime(serial)
+	} else {
+		parsed := false
+		for _, layout := range importDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				ts, parsed = t, true
+				break
+			}
+		}
+		if !parsed {
+			return errors.New("must be a date such as 2024-05-31 or an RFC3339 timestamp")
+		}
+	}
+	formatted := ts.UTC().Format(time.RFC3339)
+	*dest = &formatted
+	return nil
+}
+
+type importRowResult struct {
+	Row       int    `json:"row"`
+	Title     string `json:"title,omitempty"`
+	Error     string `json:"error,omitempty"`
+	RequestID *int64 `json:"requestId,omitempty"`
+}
+
+type importReport struct {
+	DryRun   bool              `json:"dryRun"`
+	Total    int               `json:"total"`
+	Valid    int               `json:"valid"`
+	Imported int               `json:"imported"`
+	Rows     []importRowResult `json:"rows"`
+}
+
+// handleImportRequests creates lots in bulk from an uploaded CSV or XLSX file.
+// Every row is validated like POST /api/requests. With dryRun=true only the
+// report is returned; otherwise the lots are created in one transaction, and
+// nothing is created if any row is invalid.
+func (a *API) handleImportRequests(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "failed to parse upload")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "failed to read file")
+		return
+	}
+	if len(data) > maxImportFileSize {
+		httputil.Error(w, http.StatusRequestEntityTooLarge, "file is too large")
+		return
+	}
+
+	rows, err := sheet.Read(header.Filename, data)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "failed to read spreadsheet: "+err.Error())
+		return
+	}
+	if len(rows) < 2 {
+		httputil.Error(w, http.StatusBadRequest, "file must contain a header row and at least one lot")
+		return
+	}
+	if len(rows)-1 > maxImportRows {
+		httputil.Error(w, http.StatusBadRequest, fmt.Sprintf("at most %d lots can be imported at once", maxImportRows))
+		return
+	}
+
+	setters := make([]func(p *createRequestPayload, value string) error, len(rows[0]))
+	columns := make([]string, len(rows[0]))
+	for i, name := range rows[0] {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		setter, ok := importColumns[normalizeHeader(name)]
+		if !ok {
+			httputil.Error(w, http.StatusBadRequest, "unknown column "+strings.TrimSpace(name))
+			return
+		}
+		setters[i] = setter
+		columns[i] = strings.TrimSpace(name)
+	}
+
+	report := importReport{DryRun: dryRun, Rows: []importRowResult{}}
+	var params []store.CreateRequestParams
+	var resultIndex []int
+	for i, row := range rows[1:] {
+		if sheet.IsBlank(row) {
+			continue
+		}
+		result := importRowResult{Row: i + 2}
+		report.Total++
+
+		var payload createRequestPayload
+		err := func() error {
+			for col, cell := range row {
+				cell = strings.TrimSpace(cell)
+				if col >= len(setters) || setters[col] == nil || cell == "" {
+					continue
+				}
+				if err := setters[col](&payload, cell); err != nil {
+					return validationError(columns[col] + ": " + err.Error())
+				}
+			}
+			return nil
+		}()
+		result.Title = payload.Title
+		if err == nil {
+			var p store.CreateRequestParams
+			if p, err = a.newRequestParams(r.Context(), user, payload); err == nil {
+				params = append(params, p)
+				resultIndex = append(resultIndex, len(report.Rows))
+				report.Valid++
+			}
+		}
+		if err != nil {
+			var invalid validationError
+			if !errors.As(err, &invalid) {
+				httputil.Error(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			result.Error = err.Error()
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if dryRun {
+		httputil.JSON(w, http.StatusOK, report)
+		return
+	}
+	if report.Valid != report.Total {
+		httputil.JSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	created, err := a.Store.CreateRequests(r.Context(), params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i, req := range created {
+		id := req.ID
+		report.Rows[resultIndex[i]].RequestID = &id
+	}
+	report.Imported = len(created)
+	httputil.JSON(w, http.StatusCreated, report)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/store"
+)
+
+const maxInvitationsPerRequest = 100
+
+var requestVisibilities = map[string]bool{"public": true, "private": true}
+
+func validateVisibility(visibility string) error {
+	if !requestVisibilities[visibility] {
+		return errors.New("visibility must be public or private")
+	}
+	return nil
+}
+
+// canViewRequest reports whether the caller may see the lot. Owners always
+// can; everyone else needs it to be published and either public, shared with
+// them through the link token in the "share" query parameter, or invited.
+func (a *API) canViewRequest(r *http.Request, req *models.Request, user *models.User) bool {
+	if isRequestOwner(req, user) {
+		return true
+	}
+	if !isPublished(req) {
+		return false
+	}
+	if req.Visibility == "public" {
+		return true
+	}
+	if shared := r.URL.Query().Get("share"); shared != "" {
+		token, err := a.Store.GetRequestShareToken(r.Context(), req.ID)
+		if err == nil && token != nil && subtle.ConstantTimeCompare([]byte(*token), []byte(shared)) == 1 {
+			return true
+		}
+	}
+	if user == nil {
+		return false
+	}
+	invited, err := a.Store.IsRequestInvitee(r.Context(), req.ID, user.ID, user.Email)
+	return err == nil && invited
+}
+
+type inviteSellersPayload struct {
+	Emails  []string `json:"emails"`
+	UserIDs []int64  `json:"userIds"`
+}
+
+func (p inviteSellersPayload) validate() error {
+	if len(p.Emails) == 0 && len(p.UserIDs) == 0 {
+		return errors.New("emails or userIds are required")
+	}
+	if len(p.Emails)+len(p.UserIDs) > maxInvitationsPerRequest {
+		return errors.New("too many invitations in one request")
+	}
+	for _, email := range p.Emails {
+		if !strings.Contains(email, "@") {
+			return errors.New("invalid email: " + email)
+		}
+	}
+	return nil
+}
+
+func (a *API) handleListRequestInvitations(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	invitations, err := a.Store.ListRequestInvitations(r.Context(), req.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, invitations)
+}
+
+func (a *API) handleInviteSellers(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	var payload inviteSellersPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := payload.validate(); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitations, err := a.Store.CreateRequestInvitations(r.Context(), req.ID, payload.Emails, payload.UserIDs)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	body := req.BuyerName + " приглашает вас сделать предложение"
+	meta, _ := json.Marshal(map[string]interface{}{
+		"requestId": req.ID,
+	})
+	for _, invitation := range invitations {
+		if invitation.UserID == nil || *invitation.UserID == user.ID {
+			continue
+		}
+		_, _ = a.Store.CreateNotification(r.Context(), store.CreateNotificationParams{
+			UserID:   *invitation.UserID,
+			Type:     "request.invitation",
+			Title:    "Приглашение к лоту " + req.Title,
+			Body:     &body,
+			Metadata: meta,
+		})
+	}
+
+	httputil.JSON(w, http.StatusCreated, invitations)
+}
+
+func (a *API) handleDeleteRequestInvitation(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+	invitationID, err := parseID(r, "invitationID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.Store.DeleteRequestInvitation(r.Context(), req.ID, invitationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "invitation not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateShareLink issues a new share token for the lot, invalidating
+// any previous link.
+func (a *API) handleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		httputil.Error(w, http.StatusInternalServerError, "failed to generate share token")
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := a.Store.SetRequestShareToken(r.Context(), req.ID, &token); err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, map[string]string{"token": token})
+}
+
+func (a *API) handleRevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := a.Store.SetRequestShareToken(r.Context(), req.ID, nil); err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/schedule"
+	"lotbuy-backend/internal/store"
+)
+
+type createSeriesPayload struct {
+	Name         string `json:"name"`
+	TemplateID   int64  `json:"templateId"`
+	Recurrence   string `json:"recurrence"`
+	DeadlineDays *int   `json:"deadlineDays"`
+}
+
+type updateSeriesPayload struct {
+	Name         *string          `json:"name"`
+	TemplateID   *int64           `json:"templateId"`
+	Recurrence   *string          `json:"recurrence"`
+	DeadlineDays *json.RawMessage `json:"deadlineDays"`
+	Active       *bool            `json:"active"`
+}
+
+// seriesPricePoint is one closed deal of a series on the price trend.
+type seriesPricePoint struct {
+	RequestID    int64     `json:"requestId"`
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	UnitPrice    *float64  `json:"unitPrice,omitempty"`
+	CurrencyCode string    `json:"currencyCode"`
+}
+
+type seriesDetail struct {
+	models.RequestSeries
+	Occurrences []models.SeriesOccurrence `json:"occurrences"`
+	PriceTrend  []seriesPricePoint        `json:"priceTrend"`
+	// PriceChange is the relative change in unit price between the first
+	// and the latest deal, e.g. -0.05 for five percent cheaper.
+	PriceChange *float64 `json:"priceChange,omitempty"`
+}
+
+func parseRecurrence(spec string) (string, schedule.Schedule, error) {
+	spec = strings.Join(strings.Fields(strings.ToLower(spec)), " ")
+	if spec == "" {
+		return "", schedule.Schedule{}, validationError("recurrence is required")
+	}
+	sched, err := schedule.Parse(spec)
+	if err != nil {
+		return "", schedule.Schedule{}, validationError("recurrence: " + err.Error())
+	}
+	if sched.Next(time.Now()).IsZero() {
+		return "", schedule.Schedule{}, validationError("recurrence never fires")
+	}
+	return spec, sched, nil
+}
+
+func validateDeadlineDays(days *int) error {
+	if days != nil && (*days <= 0 || *days > 365) {
+		return validationError("deadlineDays must be between 1 and 365")
+	}
+	return nil
+}
+
+// checkSeriesTemplate verifies that the user may publish lots from the
+// template: their own or one shared with an organization they belong to.
+func (a *API) checkSeriesTemplate(ctx context.Context, user *models.User, templateID int64) error {
+	tmpl, err := a.Store.GetRequestTemplate(ctx, templateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return validationError("template not found")
+	}
+	if err != nil {
+		return err
+	}
+	return a.checkTemplateAccess(ctx, user, tmpl)
+}
+
+func (a *API) checkTemplateAccess(ctx context.Context, user *models.User, tmpl *models.RequestTemplate) error {
+	if tmpl.UserID == user.ID {
+		return nil
+	}
+	if tmpl.OrganizationID != nil {
+		role, err := a.Store.GetOrganizationRole(ctx, *tmpl.OrganizationID, user.ID)
+		if err != nil {
+			return err
+		}
+		if role != "" {
+			return nil
+		}
+	}
+	return validationError("template not found")
+}
+
+func (a *API) loadSeries(w http.ResponseWriter, r *http.Request, user *models.User) (*models.RequestSeries, bool) {
+	id, err := parseID(r, "seriesID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	series, err := a.Store.GetRequestSeries(r.Context(), id)
+	if err != nil || series.UserID != user.ID {
+		httputil.Error(w, http.StatusNotFound, "series not found")
+		return nil, false
+	}
+	return series, true
+}
+
+func (a *API) handleListRequestSeries(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	list, err := a.Store.ListRequestSeries(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, list)
+}
+
+func (a *API) handleCreateRequestSeries(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var payload createSeriesPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		httputil.Error(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if payload.TemplateID <= 0 {
+		httputil.Error(w, http.StatusBadRequest, "templateId is required")
+		return
+	}
+	recurrence, sched, err := parseRecurrence(payload.Recurrence)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := validateDeadlineDays(payload.DeadlineDays); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := a.checkSeriesTemplate(r.Context(), user, payload.TemplateID); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	next := sched.Next(time.Now())
+	series, err := a.Store.CreateRequestSeries(r.Context(), store.CreateRequestSeriesParams{
+		UserID:       user.ID,
+		TemplateID:   payload.TemplateID,
+		Name:         name,
+		Recurrence:   recurrence,
+		DeadlineDays: payload.DeadlineDays,
+		NextRunAt:    &next,
+	})
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, series)
+}
+
+// handleGetRequestSeries returns the series with every lot it published and
+// the price trend of the deals they closed with.
+func (a *API) handleGetRequestSeries(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	series, ok := a.loadSeries(w, r, user)
+	if !ok {
+		return
+	}
+
+	occurrences, err := a.Store.ListSeriesOccurrences(r.Context(), series.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	detail := seriesDetail{RequestSeries: *series, Occurrences: occurrences, PriceTrend: []seriesPricePoint{}}
+	for _, occurrence := range occurrences {
+		if occurrence.DealID == nil || occurrence.DealAmount == nil {
+			continue
+		}
+		point := seriesPricePoint{
+			RequestID:    occurrence.RequestID,
+			Date:         occurrence.PublishedAt,
+			Amount:       *occurrence.DealAmount,
+			UnitPrice:    occurrence.DealUnitPrice,
+			CurrencyCode: occurrence.CurrencyCode,
+		}
+		if occurrence.DealCreatedAt != nil {
+			point.Date = *occurrence.DealCreatedAt
+		}
+		if occurrence.DealCurrency != nil {
+			point.CurrencyCode = *occurrence.DealCurrency
+		}
+		detail.PriceTrend = append(detail.PriceTrend, point)
+	}
+	if n := len(detail.PriceTrend); n > 1 {
+		first, last := detail.PriceTrend[0], detail.PriceTrend[n-1]
+		if first.UnitPrice != nil && last.UnitPrice != nil && *first.UnitPrice > 0 && first.CurrencyCode == last.CurrencyCode {
+			change := math.Round((*last.UnitPrice / *first.UnitPrice - 1)*10000) / 10000
+			detail.PriceChange = &change
+		}
+	}
+
+	httputil.JSON(w, http.StatusOK, detail)
+}
+
+func (a *API) handleUpdateRequestSeries(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	series, ok := a.loadSeries(w, r, user)
+	if !ok {
+		return
+	}
+
+	var payload updateSeriesPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params := store.UpdateRequestSeriesParams{ID: series.ID, UserID: user.ID}
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			httputil.Error(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		params.Name = &name
+	}
+	if payload.TemplateID != nil {
+		if err := a.checkSeriesTemplate(r.Context(), user, *payload.TemplateID); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		params.TemplateID = payload.TemplateID
+	}
+	if payload.DeadlineDays != nil {
+		var days *int
+		if err := json.Unmarshal(*payload.DeadlineDays, &days); err != nil {
+			httputil.Error(w, http.StatusBadRequest, "deadlineDays must be a number or null")
+			return
+		}
+		if err := validateDeadlineDays(days); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		params.DeadlineDays = &sql.NullInt64{}
+		if days != nil {
+			params.DeadlineDays = &sql.NullInt64{Int64: int64(*days), Valid: true}
+		}
+	}
+
+	recurrence := series.Recurrence
+	if payload.Recurrence != nil {
+		spec, _, err := parseRecurrence(*payload.Recurrence)
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		recurrence = spec
+		params.Recurrence = &spec
+	}
+	params.Active = payload.Active
+	if params.Active != nil && *params.Active && series.TemplateID == nil && params.TemplateID == nil {
+		httputil.Error(w, http.StatusBadRequest, "series has no template; choose one before resuming it")
+		return
+	}
+	if params.Recurrence != nil || params.Active != nil {
+		active := series.Active
+		if params.Active != nil {
+			active = *params.Active
+		}
+		if active {
+			sched, err := schedule.Parse(recurrence)
+			if err != nil {
+				httputil.Error(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			next := sched.Next(time.Now())
+			params.NextRunAt = &next
+			params.Active = &active
+		}
+	}
+
+	if params.Name == nil && params.TemplateID == nil && params.DeadlineDays == nil && params.Recurrence == nil && params.Active == nil {
+		httputil.Error(w, http.StatusBadRequest, "no fields provided for update")
+		return
+	}
+
+	updated, err := a.Store.UpdateRequestSeries(r.Context(), params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+func (a *API) handleDeleteRequestSeries(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	series, ok := a.loadSeries(w, r, user)
+	if !ok {
+		return
+	}
+	if err := a.Store.DeleteRequestSeries(r.Context(), series.ID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "series not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SeriesRequestParams builds the lot for the next occurrence of a series. It
+// goes through the same validation as a lot created by hand from the
+// template.
+func (a *API) SeriesRequestParams(ctx context.Context, series *models.RequestSeries, now time.Time) (store.CreateRequestParams, error) {
+	if series.TemplateID == nil {
+		return store.CreateRequestParams{}, errors.New("template deleted")
+	}
+	user, err := a.Store.GetUserByID(ctx, series.UserID)
+	if err != nil {
+		return store.CreateRequestParams{}, err
+	}
+	tmpl, err := a.Store.GetRequestTemplate(ctx, *series.TemplateID)
+	if err != nil {
+		return store.CreateRequestParams{}, err
+	}
+	if err := a.checkTemplateAccess(ctx, user, tmpl); err != nil {
+		return store.CreateRequestParams{}, err
+	}
+
+	var dates instantiateTemplatePayload
+	if series.DeadlineDays != nil {
+		deadline := now.AddDate(0, 0, *series.DeadlineDays).UTC().Format(time.RFC3339)
+		dates.DeadlineAt = &deadline
+	}
+	params, err := a.requestParamsFromTemplate(ctx, user, tmpl, dates)
+	if err != nil {
+		return store.CreateRequestParams{}, err
+	}
+	params.SeriesID = &series.ID
+	return params, nil
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 90
+)
+
+// viewerKey identifies who is viewing a lot for view deduplication: the user
+// when signed in, otherwise a hash of the address and user agent. Client
+// supplied identifiers are not trusted, since a fresh one per request would
+// inflate the count. Only a hash is stored for anonymous viewers.
+func viewerKey(r *http.Request, user *models.User) string {
+	if user != nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(host + "|" + r.UserAgent()))
+	return "anon:" + hex.EncodeToString(sum[:16])
+}
+
+// recordView counts a view of the lot; owners looking at their own lot are
+// not counted.
+func (a *API) recordView(r *http.Request, req *models.Request, user *models.User) {
+	if user != nil && isRequestOwner(req, user) {
+		return
+	}
+	_, _ = a.Store.RecordRequestView(r.Context(), req.ID, viewerKey(r, user))
+}
+
+func (a *API) handleRequestStats(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	days := defaultStatsDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 || value > maxStatsDays {
+			httputil.Error(w, http.StatusBadRequest, "days must be between 1 and "+strconv.Itoa(maxStatsDays))
+			return
+		}
+		days = value
+	}
+
+	stats, err := a.Store.GetRequestStats(r.Context(), req.ID, days)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, stats)
+}
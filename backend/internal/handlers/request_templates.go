@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/store"
+)
+
+type createTemplatePayload struct {
+	Name           string                `json:"name"`
+	OrganizationID *int64                `json:"organizationId"`
+	Lot            *createRequestPayload `json:"lot"`
+}
+
+type saveTemplatePayload struct {
+	Name           string `json:"name"`
+	OrganizationID *int64 `json:"organizationId"`
+}
+
+type updateTemplatePayload struct {
+	Name           *string               `json:"name"`
+	OrganizationID *json.RawMessage      `json:"organizationId"`
+	Lot            *createRequestPayload `json:"lot"`
+}
+
+// instantiateTemplatePayload carries the per-lot values a template leaves
+// open.
+type instantiateTemplatePayload struct {
+	Draft      bool    `json:"draft"`
+	PublishAt  *string `json:"publishAt"`
+	DeadlineAt *string `json:"deadlineAt"`
+}
+
+// lotPayloadFromRequest converts a lot back into the creation payload, leaving
+// out its dates.
+func lotPayloadFromRequest(req *models.Request) createRequestPayload {
+	quantity, unit, visibility, offerVisibility := req.Quantity, req.Unit, req.Visibility, req.OfferVisibility
+	return createRequestPayload{
+		Title:           req.Title,
+		Description:     req.Description,
+		BudgetAmount:    req.BudgetAmount,
+		CurrencyCode:    req.CurrencyCode,
+		ImageURL:        req.ImageURL,
+		Category:        req.Category,
+		Subcategory:     req.Subcategory,
+		LocationCity:    req.LocationCity,
+		LocationRegion:  req.LocationRegion,
+		LocationCountry: req.LocationCountry,
+		Attributes:      req.Attributes,
+		Condition:       req.Condition,
+		Quantity:        &quantity,
+		Unit:            &unit,
+		Latitude:        req.Latitude,
+		Longitude:       req.Longitude,
+		Visibility:      &visibility,
+		OfferVisibility: &offerVisibility,
+		lotModePayload:  lotModePayloadFromRequest(req),
+	}
+}
+
+// encodeTemplateLot validates a template lot as leniently as a draft and
+// returns it normalized for storage. Dates belong to each lot created from
+// the template, not to the template itself.
+func (a *API) encodeTemplateLot(ctx context.Context, lot createRequestPayload) ([]byte, error) {
+	lot.Title = strings.TrimSpace(lot.Title)
+	lot.Draft = false
+	lot.PublishAt = nil
+	lot.DeadlineAt = nil
+	lot.AuctionEndsAt = nil
+	lot.SealedUntil = nil
+
+	draft := lot
+	draft.Draft = true
+	if err := draft.validate(); err != nil {
+		return nil, validationError(err.Error())
+	}
+	selection, err := a.selectCategory(ctx, trimmedOrNil(lot.Category), trimmedOrNil(lot.Subcategory))
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := checkAttributes(selection, lot.Attributes, true)
+	if err != nil {
+		return nil, err
+	}
+	lot.Category, lot.Subcategory, lot.Attributes = selection.Category, selection.Subcategory, attributes
+
+	return json.Marshal(lot)
+}
+
+// checkTemplateOrganization verifies that the user may share templates with
+// the organization.
+func (a *API) checkTemplateOrganization(ctx context.Context, user *models.User, orgID int64) error {
+	role, err := a.Store.GetOrganizationRole(ctx, orgID, user.ID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return validationError("you are not a member of this organization")
+	}
+	return nil
+}
+
+// loadTemplate fetches the template in the route. Owners may use and modify
+// it; members of the organization it is shared with may only use it.
+func (a *API) loadTemplate(w http.ResponseWriter, r *http.Request, user *models.User, modify bool) (*models.RequestTemplate, bool) {
+	id, err := parseID(r, "templateID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	tmpl, err := a.Store.GetRequestTemplate(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "template not found")
+		return nil, false
+	}
+	if tmpl.UserID == user.ID {
+		return tmpl, true
+	}
+	if tmpl.OrganizationID != nil {
+		role, err := a.Store.GetOrganizationRole(r.Context(), *tmpl.OrganizationID, user.ID)
+		if err != nil {
+			httputil.Error(w, http.StatusInternalServerError, err.Error())
+			return nil, false
+		}
+		if role != "" {
+			if modify {
+				httputil.Error(w, http.StatusForbidden, "only the template owner can modify it")
+				return nil, false
+			}
+			return tmpl, true
+		}
+	}
+	httputil.Error(w, http.StatusNotFound, "template not found")
+	return nil, false
+}
+
+func (a *API) handleListRequestTemplates(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	templates, err := a.Store.ListRequestTemplates(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, templates)
+}
+
+func (a *API) handleGetRequestTemplate(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	tmpl, ok := a.loadTemplate(w, r, user, false)
+	if !ok {
+		return
+	}
+	httputil.JSON(w, http.StatusOK, tmpl)
+}
+
+func (a *API) createTemplate(w http.ResponseWriter, r *http.Request, user *models.User, name string, orgID *int64, lot createRequestPayload) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		httputil.Error(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if orgID != nil {
+		if err := a.checkTemplateOrganization(r.Context(), user, *orgID); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+	}
+	encoded, err := a.encodeTemplateLot(r.Context(), lot)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tmpl, err := a.Store.CreateRequestTemplate(r.Context(), store.CreateRequestTemplateParams{
+		UserID:         user.ID,
+		OrganizationID: orgID,
+		Name:           name,
+		Lot:            encoded,
+	})
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, tmpl)
+}
+
+func (a *API) handleCreateRequestTemplate(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	var payload createTemplatePayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if payload.Lot == nil {
+		httputil.Error(w, http.StatusBadRequest, "lot is required")
+		return
+	}
+	a.createTemplate(w, r, user, payload.Name, payload.OrganizationID, *payload.Lot)
+}
+
+// handleSaveRequestAsTemplate creates a template from one of the user's lots.
+func (a *API) handleSaveRequestAsTemplate(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	req, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+	var payload saveTemplatePayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	a.createTemplate(w, r, user, payload.Name, payload.OrganizationID, lotPayloadFromRequest(req))
+}
+
+func (a *API) handleUpdateRequestTemplate(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	tmpl, ok := a.loadTemplate(w, r, user, true)
+	if !ok {
+		return
+	}
+
+	var payload updateTemplatePayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params := store.UpdateRequestTemplateParams{ID: tmpl.ID, UserID: user.ID}
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			httputil.Error(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		params.Name = &name
+	}
+	if payload.OrganizationID != nil {
+		var orgID *int64
+		if err := json.Unmarshal(*payload.OrganizationID, &orgID); err != nil {
+			httputil.Error(w, http.StatusBadRequest, "organizationId must be a number or null")
+			return
+		}
+		params.OrganizationID = &sql.NullInt64{}
+		if orgID != nil {
+			if err := a.checkTemplateOrganization(r.Context(), user, *orgID); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			params.OrganizationID = &sql.NullInt64{Int64: *orgID, Valid: true}
+		}
+	}
+	if payload.Lot != nil {
+		encoded, err := a.encodeTemplateLot(r.Context(), *payload.Lot)
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		params.Lot = &encoded
+	}
+	if params.Name == nil && params.OrganizationID == nil && params.Lot == nil {
+		httputil.Error(w, http.StatusBadRequest, "no fields provided for update")
+		return
+	}
+
+	updated, err := a.Store.UpdateRequestTemplate(r.Context(), params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, updated)
+}
+
+func (a *API) handleDeleteRequestTemplate(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	tmpl, ok := a.loadTemplate(w, r, user, true)
+	if !ok {
+		return
+	}
+	if err := a.Store.DeleteRequestTemplate(r.Context(), tmpl.ID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "template not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestParamsFromTemplate builds a lot for the user from a template, filling
+// in the dates given by the caller.
+func (a *API) requestParamsFromTemplate(ctx context.Context, user *models.User, tmpl *models.RequestTemplate, dates instantiateTemplatePayload) (store.CreateRequestParams, error) {
+	var lot createRequestPayload
+	if err := json.Unmarshal(tmpl.Lot, &lot); err != nil {
+		return store.CreateRequestParams{}, errors.New("template is corrupted")
+	}
+	lot.Draft = dates.Draft
+	lot.PublishAt = dates.PublishAt
+	lot.DeadlineAt = dates.DeadlineAt
+	return a.newRequestParams(ctx, user, lot)
+}
+
+func (a *API) handleCreateRequestFromTemplate(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	tmpl, ok := a.loadTemplate(w, r, user, false)
+	if !ok {
+		return
+	}
+
+	var payload instantiateTemplatePayload
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &payload); err != nil {
+			httputil.Error(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	params, err := a.requestParamsFromTemplate(r.Context(), user, tmpl, payload)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	req, err := a.Store.CreateRequest(r.Context(), params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, req)
+}
+
+// handleDuplicateRequest clones one of the user's lots, images included, as a
+// new draft without dates or offers.
+func (a *API) handleDuplicateRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	source, ok := a.loadOwnedRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	lot := lotPayloadFromRequest(source)
+	lot.Draft = true
+	params, err := a.newRequestParams(r.Context(), user, lot)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	req, err := a.Store.DuplicateRequest(r.Context(), source.ID, params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, req)
+}
@@ -1,36 +1,143 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"lotbuy-backend/internal/geo"
 	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
 	"lotbuy-backend/internal/store"
 )
 
+// createRequestPayload is the body of POST /api/requests. Lot templates are
+// stored in the same shape, hence the omitempty tags.
 type createRequestPayload struct {
-	Title           string  `json:"title"`
-	Description     *string `json:"description"`
-	BudgetAmount    float64 `json:"budgetAmount"`
-	CurrencyCode    string  `json:"currencyCode"`
-	ImageURL        *string `json:"imageUrl"`
-	Category        *string `json:"category"`
-	Subcategory     *string `json:"subcategory"`
-	LocationCity    *string `json:"locationCity"`
-	LocationRegion  *string `json:"locationRegion"`
-	LocationCountry *string `json:"locationCountry"`
-	DeadlineAt      *string `json:"deadlineAt"`
+	Title           string          `json:"title,omitempty"`
+	Description     *string         `json:"description,omitempty"`
+	BudgetAmount    float64         `json:"budgetAmount,omitempty"`
+	CurrencyCode    string          `json:"currencyCode,omitempty"`
+	ImageURL        *string         `json:"imageUrl,omitempty"`
+	Category        *string         `json:"category,omitempty"`
+	Subcategory     *string         `json:"subcategory,omitempty"`
+	LocationCity    *string         `json:"locationCity,omitempty"`
+	LocationRegion  *string         `json:"locationRegion,omitempty"`
+	LocationCountry *string         `json:"locationCountry,omitempty"`
+	DeadlineAt      *string         `json:"deadlineAt,omitempty"`
+	PublishAt       *string         `json:"publishAt,omitempty"`
+	Draft           bool            `json:"draft,omitempty"`
+	Attributes      json.RawMessage `json:"attributes,omitempty"`
+	Condition       *string         `json:"condition,omitempty"`
+	Quantity        *int            `json:"quantity,omitempty"`
+	Unit            *string         `json:"unit,omitempty"`
+	Latitude        *float64        `json:"latitude,omitempty"`
+	Longitude       *float64        `json:"longitude,omitempty"`
+	Visibility      *string         `json:"visibility,omitempty"`
+	OfferVisibility *string         `json:"offerVisibility,omitempty"`
+	lotModePayload
+}
+
+// itemConditions are the condition grades a buyer can ask for; "any" means the
+// condition does not matter.
+var itemConditions = map[string]bool{"new": true, "like-new": true, "good": true, "fair": true, "any": true}
+
+var quantityUnits = map[string]bool{"pcs": true, "set": true, "pack": true, "kg": true, "l": true, "m": true, "hour": true}
+
+func validateCondition(condition string) error {
+	if !itemConditions[condition] {
+		return errors.New("condition must be one of new, like-new, good, fair, any")
+	}
+	return nil
+}
+
+func validateQuantity(quantity int, unit string) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be greater than zero")
+	}
+	if !quantityUnits[unit] {
+		return errors.New("unit must be one of pcs, set, pack, kg, l, m, hour")
+	}
+	return nil
+}
+
+func validateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude and longitude must be provided together")
+	}
+	if latitude != nil && !(geo.Point{Latitude: *latitude, Longitude: *longitude}).Valid() {
+		return errors.New("coordinates are out of range")
+	}
+	return nil
+}
+
+// locatePoint prefers coordinates supplied by the client and otherwise
+// geocodes the city against the bundled dataset.
+func locatePoint(latitude, longitude *float64, city, country *string) *geo.Point {
+	if latitude != nil && longitude != nil {
+		return &geo.Point{Latitude: *latitude, Longitude: *longitude}
+	}
+	if city == nil {
+		return nil
+	}
+	countryName := ""
+	if country != nil {
+		countryName = *country
+	}
+	if point, ok := geo.LookupCity(*city, countryName); ok {
+		return &point
+	}
+	return nil
 }
 
 func (p createRequestPayload) validate() error {
 	if p.Title == "" {
 		return errors.New("title is required")
 	}
+	if p.Condition != nil && strings.TrimSpace(*p.Condition) != "" {
+		if err := validateCondition(strings.ToLower(strings.TrimSpace(*p.Condition))); err != nil {
+			return err
+		}
+	}
+	if p.Quantity != nil || p.Unit != nil {
+		quantity, unit := p.quantityAndUnit()
+		if err := validateQuantity(quantity, unit); err != nil {
+			return err
+		}
+	}
+	if err := validateCoordinates(p.Latitude, p.Longitude); err != nil {
+		return err
+	}
+	if err := p.lotModePayload.validate(p.Draft); err != nil {
+		return err
+	}
+	if p.Visibility != nil {
+		if err := validateVisibility(*p.Visibility); err != nil {
+			return err
+		}
+	}
+	if p.OfferVisibility != nil {
+		if err := validateOfferVisibility(*p.OfferVisibility, p.lotModePayload.mode()); err != nil {
+			return err
+		}
+	}
+	if p.Draft {
+		if p.PublishAt != nil {
+			return errors.New("publishAt cannot be set on a draft; publish it instead")
+		}
+		if p.BudgetAmount < 0 {
+			return errors.New("budgetAmount cannot be negative")
+		}
+		return nil
+	}
 	if p.CurrencyCode == "" {
 		return errors.New("currencyCode is required")
 	}
@@ -40,6 +147,20 @@ func (p createRequestPayload) validate() error {
 	return nil
 }
 
+// quantityAndUnit applies the defaults of a single piece when the buyer did
+// not specify how many units they need.
+func (p createRequestPayload) quantityAndUnit() (int, string) {
+	quantity := 1
+	if p.Quantity != nil {
+		quantity = *p.Quantity
+	}
+	unit := "pcs"
+	if p.Unit != nil && strings.TrimSpace(*p.Unit) != "" {
+		unit = strings.ToLower(strings.TrimSpace(*p.Unit))
+	}
+	return quantity, unit
+}
+
 func (a *API) handleCreateRequest(w http.ResponseWriter, r *http.Request) {
 	user, ok := a.requireAuth(w, r)
 	if !ok {
@@ -51,11 +172,51 @@ func (a *API) handleCreateRequest(w http.ResponseWriter, r *http.Request) {
 		httputil.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := payload.validate(); err != nil {
-		httputil.Error(w, http.StatusBadRequest, err.Error())
+
+	params, err := a.newRequestParams(r.Context(), user, payload)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	req, err := a.Store.CreateRequest(r.Context(), params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	httputil.JSON(w, http.StatusCreated, req)
+}
+
+// newRequestParams validates a lot payload and resolves it into the store
+// parameters for the user's new lot. Input problems are reported as
+// validationError.
+func (a *API) newRequestParams(ctx context.Context, user *models.User, payload createRequestPayload) (store.CreateRequestParams, error) {
+	if err := payload.validate(); err != nil {
+		return store.CreateRequestParams{}, validationError(err.Error())
+	}
+
+	selection, err := a.selectCategory(ctx, trimmedOrNil(payload.Category), trimmedOrNil(payload.Subcategory))
+	if err != nil {
+		return store.CreateRequestParams{}, err
+	}
+	attributes, err := checkAttributes(selection, payload.Attributes, payload.Draft)
+	if err != nil {
+		return store.CreateRequestParams{}, err
+	}
+
+	var condition *string
+	if trimmed := trimmedOrNil(payload.Condition); trimmed != nil {
+		value := strings.ToLower(*trimmed)
+		condition = &value
+	}
+	quantity, unit := payload.quantityAndUnit()
+
+	var latitude, longitude *float64
+	if point := locatePoint(payload.Latitude, payload.Longitude, trimmedOrNil(payload.LocationCity), trimmedOrNil(payload.LocationCountry)); point != nil {
+		latitude, longitude = &point.Latitude, &point.Longitude
+	}
+
 	buyerName := user.FullName
 	if buyerName == "" {
 		buyerName = user.Email
@@ -71,7 +232,35 @@ func (a *API) handleCreateRequest(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	req, err := a.Store.CreateRequest(r.Context(), store.CreateRequestParams{
+	publishAt, err := parseOptionalTime(payload.PublishAt)
+	if err != nil {
+		return store.CreateRequestParams{}, validationError("publishAt must be an RFC3339 string")
+	}
+	status := "open"
+	switch {
+	case payload.Draft:
+		status = "draft"
+	case publishAt != nil && publishAt.After(time.Now()):
+		status = "scheduled"
+	default:
+		publishAt = nil
+	}
+
+	visibility := "public"
+	if payload.Visibility != nil {
+		visibility = *payload.Visibility
+	}
+	offerVisibility := "summary"
+	if payload.OfferVisibility != nil {
+		offerVisibility = *payload.OfferVisibility
+	}
+
+	modeSettings, err := payload.lotModePayload.settings(status, publishAt)
+	if err != nil {
+		return store.CreateRequestParams{}, validationError(err.Error())
+	}
+
+	return store.CreateRequestParams{
 		Title:           payload.Title,
 		Description:     payload.Description,
 		BudgetAmount:    payload.BudgetAmount,
@@ -81,21 +270,80 @@ func (a *API) handleCreateRequest(w http.ResponseWriter, r *http.Request) {
 		BuyerAvatar:     user.AvatarURL,
 		BuyerRating:     nil,
 		ImageURL:        payload.ImageURL,
-		Category:        payload.Category,
-		Subcategory:     payload.Subcategory,
+		Category:        selection.Category,
+		Subcategory:     selection.Subcategory,
 		LocationCity:    payload.LocationCity,
 		LocationRegion:  payload.LocationRegion,
 		LocationCountry: payload.LocationCountry,
+		Latitude:        latitude,
+		Longitude:       longitude,
 		DeadlineAt:      deadline,
-	})
+		Condition:       condition,
+		Quantity:        quantity,
+		Unit:            unit,
+		Status:          status,
+		Visibility:      visibility,
+		OfferVisibility: offerVisibility,
+		PublishAt:       publishAt,
+		Attributes:      attributes,
+		ModeSettings:    modeSettings,
+	}, nil
+}
+
+func trimmedOrNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func toNullString(value *string) *sql.NullString {
+	if value == nil {
+		return &sql.NullString{Valid: false}
+	}
+	return &sql.NullString{String: *value, Valid: true}
+}
+
+func parseOptionalTime(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil, nil
+	}
+	ts, err := time.Parse(time.RFC3339, trimmed)
 	if err != nil {
-		httputil.Error(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, err
 	}
+	return &ts, nil
+}
 
-	httputil.JSON(w, http.StatusCreated, req)
+// isPublished reports whether the lot is visible to users other than its owner.
+func isPublished(req *models.Request) bool {
+	return req.Status != "draft" && req.Status != "scheduled"
+}
+
+func isRequestOwner(req *models.Request, user *models.User) bool {
+	return user != nil && req.BuyerID != nil && *req.BuyerID == user.ID
 }
 
+const (
+	defaultSearchRadiusKm = 50
+	maxSearchRadiusKm     = 5000
+)
+
 func (a *API) handleListRequests(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
 	owner := r.URL.Query().Get("owner")
@@ -104,6 +352,38 @@ func (a *API) handleListRequests(w http.ResponseWriter, r *http.Request) {
 	if status != "" {
 		params.Status = &status
 	}
+	if category := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("category"))); category != "" {
+		params.Category = &category
+	}
+	if subcategory := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("subcategory"))); subcategory != "" {
+		params.Subcategory = &subcategory
+	}
+	if condition := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("condition"))); condition != "" {
+		params.Condition = &condition
+	}
+	if minQuantity := r.URL.Query().Get("minQuantity"); minQuantity != "" {
+		if v, err := strconv.Atoi(minQuantity); err == nil {
+			params.MinQuantity = &v
+		}
+	}
+	if near := r.URL.Query().Get("near"); near != "" {
+		point, err := geo.ParsePoint(near)
+		if err != nil {
+			httputil.Error(w, http.StatusBadRequest, "near must be lat,lng: "+err.Error())
+			return
+		}
+		radius := float64(defaultSearchRadiusKm)
+		if radiusParam := r.URL.Query().Get("radiusKm"); radiusParam != "" {
+			v, err := strconv.ParseFloat(radiusParam, 64)
+			if err != nil || v <= 0 || v > maxSearchRadiusKm {
+				httputil.Error(w, http.StatusBadRequest, fmt.Sprintf("radiusKm must be between 0 and %d", maxSearchRadiusKm))
+				return
+			}
+			radius = v
+		}
+		params.Near = &point
+		params.RadiusKm = radius
+	}
 	if limitParam != "" {
 		if v, err := strconv.Atoi(limitParam); err == nil {
 			params.Limit = &v
@@ -115,6 +395,11 @@ func (a *API) handleListRequests(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		params.BuyerID = &user.ID
+		params.IncludeUnpublished = true
+	}
+	if viewer := a.optionalUser(r); viewer != nil {
+		params.ViewerID = &viewer.ID
+		params.ViewerEmail = viewer.Email
 	}
 
 	requests, err := a.Store.ListRequests(r.Context(), params)
@@ -138,8 +423,94 @@ func (a *API) handleGetRequest(w http.ResponseWriter, r *http.Request) {
 		httputil.Error(w, http.StatusNotFound, err.Error())
 		return
 	}
+	viewer := a.optionalUser(r)
+	if !a.canViewRequest(r, req, viewer) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	a.recordView(r, req, viewer)
 
-	httputil.JSON(w, http.StatusOK, req)
+	images, err := a.Store.ListRequestImages(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	req.Images = images
+
+	httputil.JSONWithETag(w, r, http.StatusOK, req, requestETag(req))
+}
+
+type publishRequestPayload struct {
+	PublishAt *string `json:"publishAt"`
+}
+
+func (a *API) handlePublishRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var payload publishRequestPayload
+	if err := decodeJSON(r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	publishAt, err := parseOptionalTime(payload.PublishAt)
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, "publishAt must be an RFC3339 string")
+		return
+	}
+
+	existing, err := a.Store.GetRequest(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	if !isRequestOwner(existing, user) {
+		httputil.Error(w, http.StatusForbidden, "you do not have permission to publish this request")
+		return
+	}
+
+	// Drafts are saved with relaxed validation, so the stored lot has to
+	// satisfy the same rules as a lot created for immediate publishing.
+	complete := createRequestPayload{
+		Title:        strings.TrimSpace(existing.Title),
+		BudgetAmount: existing.BudgetAmount,
+		CurrencyCode: existing.CurrencyCode,
+	}
+	if err := complete.validate(); err != nil {
+		httputil.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	selection, ok := a.resolveCategory(w, r, existing.Category, existing.Subcategory)
+	if !ok {
+		return
+	}
+	if _, ok := validateAttributes(w, selection, existing.Attributes, false); !ok {
+		return
+	}
+	if err := checkModePublish(existing, publishAt); err != nil {
+		httputil.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	published, err := a.Store.PublishRequest(r.Context(), id, user.ID, publishAt)
+	if err != nil {
+		if errors.Is(err, store.ErrRequestNotDraft) {
+			httputil.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputil.JSON(w, http.StatusOK, published)
 }
 
 type updateRequestPayload struct {
@@ -154,6 +525,14 @@ type updateRequestPayload struct {
 	LocationRegion  *json.RawMessage `json:"locationRegion"`
 	LocationCountry *json.RawMessage `json:"locationCountry"`
 	DeadlineAt      *json.RawMessage `json:"deadlineAt"`
+	Attributes      json.RawMessage  `json:"attributes"`
+	Condition       *json.RawMessage `json:"condition"`
+	Quantity        *int             `json:"quantity"`
+	Unit            *string          `json:"unit"`
+	Latitude        *float64         `json:"latitude"`
+	Longitude       *float64         `json:"longitude"`
+	Visibility      *string          `json:"visibility"`
+	OfferVisibility *string          `json:"offerVisibility"`
 }
 
 func (a *API) handleUpdateRequest(w http.ResponseWriter, r *http.Request) {
@@ -177,6 +556,10 @@ func (a *API) handleUpdateRequest(w http.ResponseWriter, r *http.Request) {
 		httputil.Error(w, http.StatusForbidden, "you do not have permission to update this request")
 		return
 	}
+	if !httputil.IfMatch(r, requestETag(existing)) {
+		httputil.PreconditionFailed(w, requestETag(existing))
+		return
+	}
 
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
@@ -187,6 +570,9 @@ func (a *API) handleUpdateRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	params := store.UpdateRequestParams{ID: id, BuyerID: user.ID}
+	if r.Header.Get("If-Match") != "" {
+		params.IfUpdatedAt = &existing.UpdatedAt
+	}
 	var updates int
 
 	if payload.Title != nil {
@@ -368,22 +754,205 @@ func (a *API) handleUpdateRequest(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := validateCoordinates(payload.Latitude, payload.Longitude); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if payload.Latitude != nil || params.LocationCity != nil || params.LocationCountry != nil {
+		if payload.Latitude != nil {
+			updates++
+		}
+		city := existing.LocationCity
+		if params.LocationCity != nil {
+			city = nullStringPtr(*params.LocationCity)
+		}
+		country := existing.LocationCountry
+		if params.LocationCountry != nil {
+			country = nullStringPtr(*params.LocationCountry)
+		}
+		params.Latitude = &sql.NullFloat64{}
+		params.Longitude = &sql.NullFloat64{}
+		if point := locatePoint(payload.Latitude, payload.Longitude, city, country); point != nil {
+			params.Latitude = &sql.NullFloat64{Float64: point.Latitude, Valid: true}
+			params.Longitude = &sql.NullFloat64{Float64: point.Longitude, Valid: true}
+		}
+	}
+
+	if payload.Condition != nil {
+		updates++
+		if *payload.Condition == nil {
+			params.Condition = &sql.NullString{Valid: false}
+		} else {
+			var value string
+			if err := json.Unmarshal(*payload.Condition, &value); err != nil {
+				httputil.Error(w, http.StatusBadRequest, "condition must be a string or null")
+				return
+			}
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value == "" {
+				params.Condition = &sql.NullString{Valid: false}
+			} else {
+				if err := validateCondition(value); err != nil {
+					httputil.Error(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				params.Condition = &sql.NullString{String: value, Valid: true}
+			}
+		}
+	}
+	if payload.Quantity != nil || payload.Unit != nil {
+		quantity, unit := existing.Quantity, existing.Unit
+		if payload.Quantity != nil {
+			quantity = *payload.Quantity
+			params.Quantity = &quantity
+			updates++
+		}
+		if payload.Unit != nil {
+			unit = strings.ToLower(strings.TrimSpace(*payload.Unit))
+			params.Unit = &unit
+			updates++
+		}
+		if err := validateQuantity(quantity, unit); err != nil {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if payload.Visibility != nil {
+		if err := validateVisibility(*payload.Visibility); err != nil {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.Visibility = payload.Visibility
+		updates++
+	}
+	if payload.OfferVisibility != nil {
+		if err := validateOfferVisibility(*payload.OfferVisibility, existing.Mode); err != nil {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.OfferVisibility = payload.OfferVisibility
+		updates++
+	}
+
+	if len(payload.Attributes) > 0 {
+		updates++
+	}
+	if params.Category != nil || params.Subcategory != nil || len(payload.Attributes) > 0 {
+		category := existing.Category
+		if params.Category != nil {
+			category = nullStringPtr(*params.Category)
+		}
+		subcategory := existing.Subcategory
+		if params.Subcategory != nil {
+			subcategory = nullStringPtr(*params.Subcategory)
+		}
+		selection, ok := a.resolveCategory(w, r, category, subcategory)
+		if !ok {
+			return
+		}
+		rawAttributes := existing.Attributes
+		if len(payload.Attributes) > 0 {
+			rawAttributes = payload.Attributes
+		}
+		attributes, ok := validateAttributes(w, selection, rawAttributes, existing.Status == "draft")
+		if !ok {
+			return
+		}
+		params.Category = toNullString(selection.Category)
+		params.Subcategory = toNullString(selection.Subcategory)
+		params.Attributes = &attributes
+	}
+
 	if updates == 0 {
 		httputil.Error(w, http.StatusBadRequest, "no fields provided for update")
 		return
 	}
 
-	updated, err := a.Store.UpdateRequest(r.Context(), params)
+	updated, revision, err := a.Store.UpdateRequest(r.Context(), params)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			httputil.Error(w, http.StatusNotFound, "request not found")
 			return
 		}
+		if errors.Is(err, store.ErrRequestModified) {
+			httputil.Error(w, http.StatusPreconditionFailed, "resource was modified; reload and try again")
+			return
+		}
+		if errors.Is(err, store.ErrBidTermsLocked) {
+			httputil.Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, store.ErrGalleryHasImages) {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, store.ErrTooManyImages) {
+			httputil.Error(w, http.StatusConflict, err.Error())
+			return
+		}
 		httputil.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	httputil.JSON(w, http.StatusOK, updated)
+	a.notifyWatchersOfUpdate(r.Context(), existing, updated)
+	if revision != nil && revision.Material {
+		a.notifyBiddingSellers(r.Context(), updated, revision)
+	}
+
+	httputil.JSONWithETag(w, r, http.StatusOK, updated, requestETag(updated))
+}
+
+// requestETag identifies the version of a lot by its updated_at, which every
+// change to the lot or its gallery moves. Derived figures such as the watcher
+// count are not part of the version.
+func requestETag(req *models.Request) string {
+	return httputil.ETag(fmt.Sprintf("r%d-%d", req.ID, req.UpdatedAt.UnixMicro()))
+}
+
+// notifyBiddingSellers warns sellers with pending offers that the lot changed
+// after they made their offer.
+func (a *API) notifyBiddingSellers(ctx context.Context, req *models.Request, revision *models.RequestRevision) {
+	var changes map[string]json.RawMessage
+	_ = json.Unmarshal(revision.Changes, &changes)
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	body := "Покупатель изменил условия лота. Проверьте, актуально ли ваше предложение."
+	meta, _ := json.Marshal(map[string]interface{}{
+		"requestId": req.ID,
+		"revision":  revision.Revision,
+		"fields":    fields,
+	})
+	_ = a.Store.NotifyPendingOfferSellers(ctx, req.ID, "request.changed", "Лот "+req.Title+" изменён", &body, meta)
+}
+
+func (a *API) handleRequestHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req, err := a.Store.GetRequest(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	if !a.canViewRequest(r, req, a.optionalUser(r)) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+
+	revisions, err := a.Store.ListRequestRevisions(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, revisions)
 }
 
 func (a *API) handleDeleteRequest(w http.ResponseWriter, r *http.Request) {
@@ -409,13 +978,61 @@ func (a *API) handleDeleteRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := a.Store.DeleteRequest(r.Context(), id, user.ID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
 			httputil.Error(w, http.StatusNotFound, "request not found")
-			return
+		case errors.Is(err, store.ErrRequestHasDeal):
+			httputil.Error(w, http.StatusConflict, err.Error())
+		default:
+			httputil.Error(w, http.StatusInternalServerError, err.Error())
 		}
-		httputil.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if isPublished(req) {
+		a.notifyWatchers(r.Context(), req, "watch.closed", "Лот снят с публикации", req.Title)
+		body := "Покупатель удалил лот " + req.Title
+		meta, _ := json.Marshal(map[string]interface{}{"requestId": req.ID})
+		_ = a.Store.NotifyPendingOfferSellers(r.Context(), req.ID, "request.deleted", "Лот удалён", &body, meta)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// handleListDeletedRequests returns the user's deleted lots that can still be
+// restored.
+func (a *API) handleListDeletedRequests(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	requests, err := a.Store.ListDeletedRequests(r.Context(), user.ID, time.Now())
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, requests)
+}
+
+func (a *API) handleRestoreRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req, err := a.Store.RestoreRequest(r.Context(), id, user.ID, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "no deleted request to restore")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"lotbuy-backend/internal/geo"
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/store"
+)
+
+// savedSearchPayload mirrors the filters accepted by GET /api/requests.
+type savedSearchPayload struct {
+	Name        string   `json:"name"`
+	Delivery    string   `json:"delivery"`
+	Category    *string  `json:"category"`
+	Subcategory *string  `json:"subcategory"`
+	Condition   *string  `json:"condition"`
+	MinQuantity *int     `json:"minQuantity"`
+	Near        *string  `json:"near"`
+	RadiusKm    *float64 `json:"radiusKm"`
+}
+
+type updateSavedSearchPayload struct {
+	Name     *string `json:"name"`
+	Delivery *string `json:"delivery"`
+}
+
+func validateDelivery(delivery string) error {
+	if delivery != "instant" && delivery != "daily" {
+		return errors.New("delivery must be instant or daily")
+	}
+	return nil
+}
+
+func (a *API) handleListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	searches, err := a.Store.ListSavedSearches(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, searches)
+}
+
+func (a *API) handleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	var payload savedSearchPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		httputil.Error(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	delivery := strings.ToLower(strings.TrimSpace(payload.Delivery))
+	if delivery == "" {
+		delivery = "instant"
+	}
+	if err := validateDelivery(delivery); err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	selection, ok := a.resolveCategory(w, r, trimmedOrNil(payload.Category), trimmedOrNil(payload.Subcategory))
+	if !ok {
+		return
+	}
+
+	params := store.CreateSavedSearchParams{
+		UserID:      user.ID,
+		Name:        name,
+		Category:    selection.Category,
+		Subcategory: selection.Subcategory,
+		MinQuantity: payload.MinQuantity,
+		Delivery:    delivery,
+	}
+	if condition := trimmedOrNil(payload.Condition); condition != nil {
+		value := strings.ToLower(*condition)
+		if err := validateCondition(value); err != nil {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.Condition = &value
+	}
+	if payload.MinQuantity != nil && *payload.MinQuantity <= 0 {
+		httputil.Error(w, http.StatusBadRequest, "minQuantity must be greater than zero")
+		return
+	}
+	if near := trimmedOrNil(payload.Near); near != nil {
+		point, err := geo.ParsePoint(*near)
+		if err != nil {
+			httputil.Error(w, http.StatusBadRequest, "near must be lat,lng: "+err.Error())
+			return
+		}
+		radius := float64(defaultSearchRadiusKm)
+		if payload.RadiusKm != nil {
+			radius = *payload.RadiusKm
+		}
+		if radius <= 0 || radius > maxSearchRadiusKm {
+			httputil.Error(w, http.StatusBadRequest, "radiusKm is out of range")
+			return
+		}
+		params.NearLatitude = &point.Latitude
+		params.NearLongitude = &point.Longitude
+		params.RadiusKm = &radius
+	} else if payload.RadiusKm != nil {
+		httputil.Error(w, http.StatusBadRequest, "radiusKm requires near")
+		return
+	}
+
+	search, err := a.Store.CreateSavedSearch(r.Context(), params)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusCreated, search)
+}
+
+func (a *API) handleUpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseID(r, "searchID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var payload updateSavedSearchPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		httputil.Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params := store.UpdateSavedSearchParams{ID: id, UserID: user.ID}
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			httputil.Error(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		params.Name = &name
+	}
+	if payload.Delivery != nil {
+		delivery := strings.ToLower(strings.TrimSpace(*payload.Delivery))
+		if err := validateDelivery(delivery); err != nil {
+			httputil.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.Delivery = &delivery
+	}
+	if params.Name == nil && params.Delivery == nil {
+		httputil.Error(w, http.StatusBadRequest, "no fields provided for update")
+		return
+	}
+
+	search, err := a.Store.UpdateSavedSearch(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "saved search not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, search)
+}
+
+func (a *API) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseID(r, "searchID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.Store.DeleteSavedSearch(r.Context(), id, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "saved search not found")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lotbuy-backend/internal/httputil"
+	"lotbuy-backend/internal/models"
+)
+
+func (a *API) handleWatchRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req, err := a.Store.GetRequest(r.Context(), id)
+	if err != nil || !a.canViewRequest(r, req, user) {
+		httputil.Error(w, http.StatusNotFound, "request not found")
+		return
+	}
+	if isRequestOwner(req, user) {
+		httputil.Error(w, http.StatusBadRequest, "you cannot watch your own request")
+		return
+	}
+
+	if err := a.Store.WatchRequest(r.Context(), user.ID, id); err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleUnwatchRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseID(r, "requestID")
+	if err != nil {
+		httputil.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.Store.UnwatchRequest(r.Context(), user.ID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.Error(w, http.StatusNotFound, "request is not in your watchlist")
+			return
+		}
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleListWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	requests, err := a.Store.ListWatchedRequests(r.Context(), user.ID)
+	if err != nil {
+		httputil.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	httputil.JSON(w, http.StatusOK, requests)
+}
+
+// notifyWatchers tells everyone watching req about a change. Delivery is best
+// effort, like the other notifications.
+func (a *API) notifyWatchers(ctx context.Context, req *models.Request, notificationType, title, body string) {
+	if req.WatcherCount == 0 {
+		return
+	}
+	meta, _ := json.Marshal(map[string]interface{}{
+		"requestId": req.ID,
+	})
+	_ = a.Store.NotifyRequestWatchers(ctx, req.ID, notificationType, title, &body, meta)
+}
+
+// notifyWatchersOfUpdate reports budget and deadline changes between two
+// versions of a published lot.
+func (a *API) notifyWatchersOfUpdate(ctx context.Context, before, after *models.Request) {
+	if !isPublished(after) {
+		return
+	}
+	if before.BudgetAmount != after.BudgetAmount || before.CurrencyCode != after.CurrencyCode {
+		a.notifyWatchers(ctx, after, "watch.budget_changed", "Изменился бюджет лота "+after.Title,
+			"Новый бюджет: "+formatAmount(after.BudgetAmount, after.CurrencyCode))
+	}
+	if !sameTime(before.DeadlineAt, after.DeadlineAt) {
+		body := "Срок лота снят"
+		if after.DeadlineAt != nil {
+			body = "Новый срок: " + after.DeadlineAt.Format("02.01.2006 15:04")
+		}
+		a.notifyWatchers(ctx, after, "watch.deadline_changed", "Изменился срок лота "+after.Title, body)
+	}
+}
+
+func formatAmount(amount float64, currency string) string {
+	return strings.TrimSpace(strconv.FormatFloat(amount, 'f', -1, 64) + " " + currency)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,71 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ETag quotes an entity version for use as a strong ETag.
+func ETag(version string) string {
+	return `"` + version + `"`
+}
+
+// etagList splits an If-Match or If-None-Match header into its entity tags.
+func etagList(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// IfMatch reports whether the request's If-Match precondition holds for the
+// current ETag. A missing header always holds; weak tags never match.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range etagList(header) {
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified reports whether the request's If-None-Match header lists the
+// current ETag, compared weakly.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range etagList(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONWithETag writes payload like JSON and sets its ETag. GET and HEAD
+// requests that already hold the current version get 304 Not Modified with no
+// body.
+func JSONWithETag(w http.ResponseWriter, r *http.Request, status int, payload interface{}, etag string) {
+	w.Header().Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	JSON(w, status, payload)
+}
+
+// PreconditionFailed responds 412 for a failed If-Match and tells the client
+// which version is current.
+func PreconditionFailed(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	Error(w, http.StatusPreconditionFailed, "resource was modified; reload and try again")
+}
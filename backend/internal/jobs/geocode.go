@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+
+	"lotbuy-backend/internal/geo"
+	"lotbuy-backend/internal/store"
+)
+
+const geocodeBatchSize = 100
+
+// GeocodeRequests fills in the coordinates of lots that name a city but have
+// none, such as lots saved before radius search existed, so they show up in
+// radius searches.
+func GeocodeRequests(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			locations, err := s.ListRequestsToGeocode(ctx, geocodeBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, l := range locations {
+				country := ""
+				if l.Country != nil {
+					country = *l.Country
+				}
+				var point *geo.Point
+				if found, ok := geo.LookupCity(l.City, country); ok {
+					point = &found
+				}
+				if err := s.SetRequestGeocoded(ctx, l.ID, point); err != nil {
+					return err
+				}
+			}
+			if len(locations) < geocodeBatchSize {
+				return nil
+			}
+		}
+	}
+}
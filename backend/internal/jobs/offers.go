@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"lotbuy-backend/internal/store"
+)
+
+// ExpireOffers closes offers whose validity has run out.
+func ExpireOffers(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.ExpireOffers(ctx, time.Now())
+		return err
+	}
+}
+
+// RevealSealedOffers tells buyers when the offers on their sealed-bid lots
+// become visible.
+func RevealSealedOffers(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.RevealSealedOffers(ctx, time.Now())
+		return err
+	}
+}
+
+// CloseAuctions ends every auction past its end time. The store picks the
+// winner and, on lots awarded automatically, opens the deal.
+func CloseAuctions(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		due, err := s.ListDueAuctions(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for i := range due {
+			if _, err := s.CloseAuction(ctx, due[i].ID); err != nil && !errors.Is(err, store.ErrAuctionRunning) {
+				log.Printf("auction %d: %v", due[i].ID, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"lotbuy-backend/internal/models"
+	"lotbuy-backend/internal/schedule"
+	"lotbuy-backend/internal/store"
+)
+
+// seriesBatchSize bounds how many due series one scheduler tick publishes.
+const seriesBatchSize = 50
+
+// SeriesLotBuilder turns the due occurrence of a series into a validated lot.
+type SeriesLotBuilder func(ctx context.Context, series *models.RequestSeries, now time.Time) (store.CreateRequestParams, error)
+
+// RunRecurringSeries publishes a lot for every series whose next occurrence
+// is due. When the lot cannot be built the occurrence is skipped, the error
+// is recorded on the series and the owner is notified.
+func RunRecurringSeries(s *store.Store, build SeriesLotBuilder) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		due, err := s.ListDueSeries(ctx, now, seriesBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range due {
+			series := &due[i]
+			if err := runSeries(ctx, s, build, series, now); err != nil {
+				log.Printf("series %d: %v", series.ID, err)
+				if err := s.FailSeriesRun(ctx, series.ID, err.Error()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func runSeries(ctx context.Context, s *store.Store, build SeriesLotBuilder, series *models.RequestSeries, now time.Time) error {
+	sched, err := schedule.Parse(series.Recurrence)
+	if err != nil {
+		return err
+	}
+	var next *time.Time
+	if ts := sched.Next(now); !ts.IsZero() {
+		next = &ts
+	}
+	claimed, err := s.ClaimSeriesRun(ctx, series.ID, *series.NextRunAt, next)
+	if err != nil || !claimed {
+		return err
+	}
+
+	params, err := build(ctx, series, now)
+	if err != nil {
+		return err
+	}
+	_, err = s.CreateRequest(ctx, params)
+	return err
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"lotbuy-backend/internal/store"
+)
+
+// PublishScheduledRequests opens lots whose scheduled publish time has passed.
+func PublishScheduledRequests(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.PublishDueRequests(ctx, time.Now())
+		return err
+	}
+}
+
+// PurgeDeletedRequests permanently removes lots whose restore window has
+// passed.
+func PurgeDeletedRequests(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.PurgeDeletedRequests(ctx, time.Now())
+		return err
+	}
+}
+
+// PruneRequestViews forgets per-viewer view records once they no longer
+// affect deduplication.
+func PruneRequestViews(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.PruneRequestViews(ctx, time.Now())
+		return err
+	}
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs registered tasks at fixed intervals until its context is
+// cancelled. Each task runs on its own goroutine and never overlaps itself.
+type Scheduler struct {
+	tasks []Task
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{tasks: make([]Task, 0)}
+}
+
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, Task{Name: name, Interval: interval, Run: run})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, task := range s.tasks {
+		go s.loop(ctx, task)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, task Task) {
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+
+	s.runOnce(ctx, task)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, task)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, task Task) {
+	if err := task.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("job %s failed: %v", task.Name, err)
+	}
+}
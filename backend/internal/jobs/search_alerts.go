@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"lotbuy-backend/internal/store"
+)
+
+const searchAlertBatchSize = 100
+
+// MatchSavedSearches drains newly published lots through the saved search
+// matcher in batches.
+func MatchSavedSearches(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			processed, err := s.ProcessSearchAlerts(ctx, searchAlertBatchSize)
+			if err != nil {
+				return err
+			}
+			if processed < searchAlertBatchSize {
+				return nil
+			}
+		}
+	}
+}
+
+// SendSearchDigests delivers the daily saved search digests that are due.
+func SendSearchDigests(s *store.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.SendSearchDigests(ctx, time.Now())
+		return err
+	}
+}
@@ -6,25 +6,131 @@ import (
 )
 
 type Request struct {
-	ID              int64      `db:"id" json:"id"`
-	Title           string     `db:"title" json:"title"`
-	Description     *string    `db:"description" json:"description,omitempty"`
-	BudgetAmount    float64    `db:"budget_amount" json:"budgetAmount"`
-	CurrencyCode    string     `db:"currency_code" json:"currencyCode"`
-	BuyerID         *int64     `db:"buyer_user_id" json:"buyerId,omitempty"`
-	BuyerName       string     `db:"buyer_name" json:"buyerName"`
-	BuyerAvatar     *string    `db:"buyer_avatar_url" json:"buyerAvatarUrl,omitempty"`
-	BuyerRating     *float64   `db:"buyer_rating" json:"buyerRating,omitempty"`
-	ImageURL        *string    `db:"image_url" json:"imageUrl,omitempty"`
-	Category        *string    `db:"category" json:"category,omitempty"`
-	Subcategory     *string    `db:"subcategory" json:"subcategory,omitempty"`
-	LocationCity    *string    `db:"location_city" json:"locationCity,omitempty"`
-	LocationRegion  *string    `db:"location_region" json:"locationRegion,omitempty"`
-	LocationCountry *string    `db:"location_country" json:"locationCountry,omitempty"`
-	DeadlineAt      *time.Time `db:"deadline_at" json:"deadlineAt,omitempty"`
-	Status          string     `db:"status" json:"status"`
-	CreatedAt       time.Time  `db:"created_at" json:"createdAt"`
-	UpdatedAt       time.Time  `db:"updated_at" json:"updatedAt"`
+	ID              int64           `db:"id" json:"id"`
+	Title           string          `db:"title" json:"title"`
+	Description     *string         `db:"description" json:"description,omitempty"`
+	BudgetAmount    float64         `db:"budget_amount" json:"budgetAmount"`
+	CurrencyCode    string          `db:"currency_code" json:"currencyCode"`
+	BuyerID         *int64          `db:"buyer_user_id" json:"buyerId,omitempty"`
+	BuyerName       string          `db:"buyer_name" json:"buyerName"`
+	BuyerAvatar     *string         `db:"buyer_avatar_url" json:"buyerAvatarUrl,omitempty"`
+	BuyerRating     *float64        `db:"buyer_rating" json:"buyerRating,omitempty"`
+	ImageURL        *string         `db:"image_url" json:"imageUrl,omitempty"`
+	Category        *string         `db:"category" json:"category,omitempty"`
+	Subcategory     *string         `db:"subcategory" json:"subcategory,omitempty"`
+	LocationCity    *string         `db:"location_city" json:"locationCity,omitempty"`
+	LocationRegion  *string         `db:"location_region" json:"locationRegion,omitempty"`
+	LocationCountry *string         `db:"location_country" json:"locationCountry,omitempty"`
+	Latitude        *float64        `db:"latitude" json:"latitude,omitempty"`
+	Longitude       *float64        `db:"longitude" json:"longitude,omitempty"`
+	DistanceKm      *float64        `db:"distance_km" json:"distanceKm,omitempty"`
+	DeadlineAt      *time.Time      `db:"deadline_at" json:"deadlineAt,omitempty"`
+	Condition       *string         `db:"condition" json:"condition,omitempty"`
+	Quantity        int             `db:"quantity" json:"quantity"`
+	Unit            string          `db:"unit" json:"unit"`
+	Status          string          `db:"status" json:"status"`
+	Visibility      string          `db:"visibility" json:"visibility"`
+	OfferVisibility string          `db:"offer_visibility" json:"offerVisibility"`
+	PublishAt       *time.Time      `db:"publish_at" json:"publishAt,omitempty"`
+	CreatedAt       time.Time       `db:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updatedAt"`
+	Attributes      json.RawMessage `db:"attributes" json:"attributes,omitempty"`
+	CoverImageURL   *string         `db:"cover_image_url" json:"coverImageUrl,omitempty"`
+	WatcherCount    int             `db:"watcher_count" json:"watcherCount"`
+	Revision        int             `db:"revision" json:"revision"`
+	SeriesID        *int64          `db:"series_id" json:"seriesId,omitempty"`
+	DeletedAt       *time.Time      `db:"deleted_at" json:"deletedAt,omitempty"`
+	// Mode is "standard", "reverse_auction" for lots where sellers bid the
+	// price down in the open, or "sealed_bid"; the Auction fields only apply
+	// to auctions.
+	Mode                    string     `db:"mode" json:"mode"`
+	AuctionEndsAt           *time.Time `db:"auction_ends_at" json:"auctionEndsAt,omitempty"`
+	AuctionDurationHours    *int       `db:"auction_duration_hours" json:"auctionDurationHours,omitempty"`
+	AuctionMinDecrement     *float64   `db:"auction_min_decrement" json:"auctionMinDecrement,omitempty"`
+	AuctionExtensionMinutes *int       `db:"auction_extension_minutes" json:"auctionExtensionMinutes,omitempty"`
+	AuctionAward            *string    `db:"auction_award" json:"auctionAward,omitempty"`
+	AuctionClosedAt         *time.Time `db:"auction_closed_at" json:"auctionClosedAt,omitempty"`
+	AuctionWinnerOfferID    *int64     `db:"auction_winner_offer_id" json:"auctionWinnerOfferId,omitempty"`
+	// SealedUntil is the bid deadline of a "sealed_bid" lot: until then
+	// offers are visible only to their sellers.
+	SealedUntil         *time.Time     `db:"sealed_until" json:"sealedUntil,omitempty"`
+	SealedDurationHours *int           `db:"sealed_duration_hours" json:"sealedDurationHours,omitempty"`
+	Images              []RequestImage `db:"-" json:"images,omitempty"`
+}
+
+// IsAuction reports whether sellers bid on the lot in an auction.
+func (r Request) IsAuction() bool {
+	return r.Mode == "reverse_auction"
+}
+
+// IsSealed reports whether offers on the lot are sealed until a deadline.
+func (r Request) IsSealed() bool {
+	return r.Mode == "sealed_bid"
+}
+
+// OffersSealed reports whether the lot's offers are still hidden from the
+// buyer at now.
+func (r Request) OffersSealed(now time.Time) bool {
+	return r.IsSealed() && (r.SealedUntil == nil || r.SealedUntil.After(now))
+}
+
+// BiddingOpen reports whether sellers are still competing on a published
+// auction or sealed-bid lot at now, so that bids must stay comparable.
+func (r Request) BiddingOpen(now time.Time) bool {
+	if r.Status != "open" {
+		return false
+	}
+	switch {
+	case r.IsAuction():
+		return r.AuctionClosedAt == nil
+	case r.IsSealed():
+		return r.OffersSealed(now)
+	}
+	return false
+}
+
+// AuctionState is the public view of a running or finished auction. Bids are
+// anonymous; Your* fields describe the caller's own bid.
+type AuctionState struct {
+	RequestID        int64      `json:"requestId"`
+	Mode             string     `json:"mode"`
+	CurrencyCode     string     `json:"currencyCode"`
+	EndsAt           *time.Time `json:"endsAt,omitempty"`
+	ClosedAt         *time.Time `json:"closedAt,omitempty"`
+	MinDecrement     float64    `json:"minDecrement"`
+	ExtensionMinutes int        `json:"extensionMinutes"`
+	Award            string     `json:"award"`
+	BidCount         *int       `json:"bidCount,omitempty"`
+	LowestBid        *float64   `json:"lowestBid,omitempty"`
+	// MaxNextBid is the highest amount a new bid may have.
+	MaxNextBid    *float64 `json:"maxNextBid,omitempty"`
+	YourBid       *float64 `json:"yourBid,omitempty"`
+	YourOfferID   *int64   `json:"yourOfferId,omitempty"`
+	Leading       bool     `json:"leading"`
+	WinnerOfferID *int64   `json:"winnerOfferId,omitempty"`
+}
+
+type Category struct {
+	ID              int64           `db:"id" json:"id"`
+	ParentID        *int64          `db:"parent_id" json:"parentId,omitempty"`
+	Slug            string          `db:"slug" json:"slug"`
+	Name            string          `db:"-" json:"name"`
+	Names           json.RawMessage `db:"names" json:"names"`
+	AttributeSchema json.RawMessage `db:"attribute_schema" json:"attributeSchema,omitempty"`
+	Position        int             `db:"position" json:"position"`
+	Children        []Category      `db:"-" json:"children,omitempty"`
+}
+
+type RequestImage struct {
+	ID        int64     `db:"id" json:"id"`
+	RequestID int64     `db:"request_id" json:"requestId"`
+	URL       string    `db:"url" json:"url"`
+	AltText   *string   `db:"alt_text" json:"altText,omitempty"`
+	Width     *int      `db:"width" json:"width,omitempty"`
+	Height    *int      `db:"height" json:"height,omitempty"`
+	Position  int       `db:"position" json:"position"`
+	IsCover   bool      `db:"is_cover" json:"isCover"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
 }
 
 type Offer struct {
@@ -37,9 +143,250 @@ type Offer struct {
 	PriceAmount  float64   `db:"price_amount" json:"priceAmount"`
 	CurrencyCode string    `db:"currency_code" json:"currencyCode"`
 	Message      *string   `db:"message" json:"message,omitempty"`
+	Quantity     int       `db:"quantity" json:"quantity"`
+	UnitPrice    *float64  `db:"unit_price" json:"unitPrice,omitempty"`
 	Status       string    `db:"status" json:"status"`
 	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
 	UpdatedAt    time.Time `db:"updated_at" json:"updatedAt"`
+	// RequestRevision is the lot revision the offer was made against;
+	// RequestOutdated reports a later material change to the lot.
+	RequestRevision int  `db:"request_revision" json:"requestRevision"`
+	RequestOutdated bool `db:"request_outdated" json:"requestOutdated"`
+	// Round is the latest negotiation round, whose terms the offer carries;
+	// Awaiting is the party (buyer or seller) expected to answer it.
+	Round    int    `db:"round" json:"round"`
+	Awaiting string `db:"awaiting" json:"awaiting"`
+	// RejectionReason is the buyer's note when rejecting the offer; ClosedAt
+	// is when it left pending.
+	RejectionReason *string    `db:"rejection_reason" json:"rejectionReason,omitempty"`
+	ClosedAt        *time.Time `db:"closed_at" json:"closedAt,omitempty"`
+	// ValidUntil is when the seller's price lapses; nil means open-ended.
+	ValidUntil *time.Time `db:"valid_until" json:"validUntil,omitempty"`
+	OfferTerms
+}
+
+// OfferListing is what a caller may see of the offers on a lot. View is the
+// caller's role on the lot: "buyer", "seller" or "guest". Offers holds the
+// offers shown in full; Count and PriceRange summarize the active ones when
+// the lot's policy shares them.
+type OfferListing struct {
+	RequestID       int64            `json:"requestId"`
+	View            string           `json:"view"`
+	OfferVisibility string           `json:"offerVisibility"`
+	Count           *int             `json:"count,omitempty"`
+	PriceRange      *OfferPriceRange `json:"priceRange,omitempty"`
+	Offers          []Offer          `json:"offers"`
+}
+
+// OfferPriceRange spans the unit prices of the active offers on a lot,
+// converted to the lot currency.
+type OfferPriceRange struct {
+	MinUnitPrice float64 `json:"minUnitPrice"`
+	MaxUnitPrice float64 `json:"maxUnitPrice"`
+	CurrencyCode string  `json:"currencyCode"`
+}
+
+// OfferTerms are the delivery, warranty and payment conditions a seller
+// quotes alongside the price. ShippingCost is in the offer's currency.
+type OfferTerms struct {
+	DeliveryDays   *int     `db:"delivery_days" json:"deliveryDays,omitempty"`
+	ShippingCost   *float64 `db:"shipping_cost" json:"shippingCost,omitempty"`
+	ShippingMethod *string  `db:"shipping_method" json:"shippingMethod,omitempty"`
+	WarrantyMonths *int     `db:"warranty_months" json:"warrantyMonths,omitempty"`
+	PaymentTerms   *string  `db:"payment_terms" json:"paymentTerms,omitempty"`
+	ItemCondition  *string  `db:"item_condition" json:"itemCondition,omitempty"`
+}
+
+// ComparableOffer is an offer with the track record of its seller, as used
+// to score offers against each other.
+type ComparableOffer struct {
+	Offer
+	SellerCompletedDeals int      `db:"seller_completed_deals"`
+	SellerAverageRating  *float64 `db:"seller_average_rating"`
+}
+
+// OfferComparison ranks the open offers on a lot, best first.
+type OfferComparison struct {
+	RequestID    int64             `json:"requestId"`
+	CurrencyCode string            `json:"currencyCode"`
+	Weights      OfferScoreWeights `json:"weights"`
+	Offers       []ScoredOffer     `json:"offers"`
+	BestValueID  *int64            `json:"bestValueOfferId,omitempty"`
+}
+
+// OfferScoreWeights are the relative weights of the scoring criteria.
+type OfferScoreWeights struct {
+	Price      float64 `db:"price_weight" json:"price"`
+	Delivery   float64 `db:"delivery_weight" json:"delivery"`
+	Rating     float64 `db:"rating_weight" json:"rating"`
+	Experience float64 `db:"experience_weight" json:"experience"`
+}
+
+// ScoredOffer is an offer with its 0-100 score and the per-criterion scores
+// it is made of. LandedCost is price plus shipping in the lot currency.
+type ScoredOffer struct {
+	Offer                Offer               `json:"offer"`
+	Rank                 int                 `json:"rank"`
+	Score                float64             `json:"score"`
+	Breakdown            OfferScoreBreakdown `json:"breakdown"`
+	LandedCost           *float64            `json:"landedCost,omitempty"`
+	UnitLandedCost       *float64            `json:"unitLandedCost,omitempty"`
+	SellerRating         *float64            `json:"sellerRating,omitempty"`
+	SellerCompletedDeals int                 `json:"sellerCompletedDeals"`
+	Warnings             []string            `json:"warnings,omitempty"`
+}
+
+type OfferScoreBreakdown struct {
+	Price      float64 `json:"price"`
+	Delivery   float64 `json:"delivery"`
+	Rating     float64 `json:"rating"`
+	Experience float64 `json:"experience"`
+}
+
+// OfferRound is one immutable proposal in the negotiation of an offer.
+type OfferRound struct {
+	ID           int64     `db:"id" json:"id"`
+	OfferID      int64     `db:"offer_id" json:"offerId"`
+	Round        int       `db:"round" json:"round"`
+	ProposedBy   string    `db:"proposed_by" json:"proposedBy"`
+	UserID       *int64    `db:"user_id" json:"userId,omitempty"`
+	PriceAmount  float64   `db:"price_amount" json:"priceAmount"`
+	CurrencyCode string    `db:"currency_code" json:"currencyCode"`
+	Quantity     int       `db:"quantity" json:"quantity"`
+	UnitPrice    *float64  `db:"unit_price" json:"unitPrice,omitempty"`
+	Message      *string   `db:"message" json:"message,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+	OfferTerms
+}
+
+// RequestInvitation grants a seller access to a private lot. UserID is set
+// once the email belongs to a registered account.
+type RequestInvitation struct {
+	ID        int64     `db:"id" json:"id"`
+	RequestID int64     `db:"request_id" json:"requestId"`
+	Email     string    `db:"email" json:"email"`
+	UserID    *int64    `db:"user_id" json:"userId,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// RequestTemplate stores the fields of a lot, in the shape of the lot
+// creation payload, for reuse. Templates with an OrganizationID are shared
+// with every member of that organization.
+type RequestTemplate struct {
+	ID             int64           `db:"id" json:"id"`
+	UserID         int64           `db:"user_id" json:"userId"`
+	OrganizationID *int64          `db:"organization_id" json:"organizationId,omitempty"`
+	Name           string          `db:"name" json:"name"`
+	Lot            json.RawMessage `db:"lot" json:"lot"`
+	CreatedAt      time.Time       `db:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time       `db:"updated_at" json:"updatedAt"`
+}
+
+// RequestStats is the owner's analytics for a lot. Views are deduplicated per
+// viewer and day.
+type RequestStats struct {
+	RequestID               int64        `db:"request_id" json:"requestId"`
+	CurrencyCode            *string      `db:"currency_code" json:"currencyCode,omitempty"`
+	Views                   int          `db:"views" json:"views"`
+	Watchers                int          `db:"watchers" json:"watchers"`
+	OfferCount              int          `db:"offer_count" json:"offerCount"`
+	MedianOfferPrice        *float64     `db:"-" json:"medianOfferPrice,omitempty"`
+	MedianUnitPrice         *float64     `db:"-" json:"medianUnitPrice,omitempty"`
+	FirstOfferAt            *time.Time   `db:"first_offer_at" json:"firstOfferAt,omitempty"`
+	TimeToFirstOfferSeconds *int64       `db:"time_to_first_offer_seconds" json:"timeToFirstOfferSeconds,omitempty"`
+	ViewsByDay              []DailyViews `db:"-" json:"viewsByDay"`
+}
+
+type DailyViews struct {
+	Day   string `db:"day" json:"day"`
+	Views int    `db:"views" json:"views"`
+}
+
+// RequestSeries publishes a new lot from a template every time its
+// recurrence fires.
+type RequestSeries struct {
+	ID           int64      `db:"id" json:"id"`
+	UserID       int64      `db:"user_id" json:"userId"`
+	TemplateID   *int64     `db:"template_id" json:"templateId"`
+	Name         string     `db:"name" json:"name"`
+	Recurrence   string     `db:"recurrence" json:"recurrence"`
+	DeadlineDays *int       `db:"deadline_days" json:"deadlineDays,omitempty"`
+	Active       bool       `db:"active" json:"active"`
+	NextRunAt    *time.Time `db:"next_run_at" json:"nextRunAt,omitempty"`
+	LastRunAt    *time.Time `db:"last_run_at" json:"lastRunAt,omitempty"`
+	LastError    *string    `db:"last_error" json:"lastError,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updatedAt"`
+}
+
+// SeriesOccurrence summarizes one lot of a series with the offers it drew
+// and the deal it ended in, if any.
+type SeriesOccurrence struct {
+	RequestID     int64      `db:"request_id" json:"requestId"`
+	Title         string     `db:"title" json:"title"`
+	Status        string     `db:"status" json:"status"`
+	BudgetAmount  float64    `db:"budget_amount" json:"budgetAmount"`
+	CurrencyCode  string     `db:"currency_code" json:"currencyCode"`
+	Quantity      int        `db:"quantity" json:"quantity"`
+	PublishedAt   time.Time  `db:"published_at" json:"publishedAt"`
+	OfferCount    int        `db:"offer_count" json:"offerCount"`
+	BestOffer     *float64   `db:"best_offer" json:"bestOffer,omitempty"`
+	DealID        *int64     `db:"deal_id" json:"dealId,omitempty"`
+	DealStatus    *string    `db:"deal_status" json:"dealStatus,omitempty"`
+	DealAmount    *float64   `db:"deal_amount" json:"dealAmount,omitempty"`
+	DealCurrency  *string    `db:"deal_currency" json:"dealCurrency,omitempty"`
+	DealUnitPrice *float64   `db:"deal_unit_price" json:"dealUnitPrice,omitempty"`
+	DealCreatedAt *time.Time `db:"deal_created_at" json:"dealCreatedAt,omitempty"`
+}
+
+type Organization struct {
+	ID        int64     `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+type OrganizationMember struct {
+	UserID    int64     `db:"user_id" json:"userId"`
+	Email     string    `db:"email" json:"email"`
+	FullName  string    `db:"full_name" json:"fullName"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// RequestRevision records one edit of a lot. Changes maps API field names to
+// FieldChange values.
+type RequestRevision struct {
+	ID            int64           `db:"id" json:"id"`
+	RequestID     int64           `db:"request_id" json:"requestId"`
+	Revision      int             `db:"revision" json:"revision"`
+	ChangedBy     *int64          `db:"changed_by" json:"changedBy,omitempty"`
+	ChangedByName *string         `db:"changed_by_name" json:"changedByName,omitempty"`
+	Changes       json.RawMessage `db:"changes" json:"changes"`
+	Material      bool            `db:"material" json:"material"`
+	CreatedAt     time.Time       `db:"created_at" json:"createdAt"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type SavedSearch struct {
+	ID            int64      `db:"id" json:"id"`
+	UserID        int64      `db:"user_id" json:"userId"`
+	Name          string     `db:"name" json:"name"`
+	Category      *string    `db:"category" json:"category,omitempty"`
+	Subcategory   *string    `db:"subcategory" json:"subcategory,omitempty"`
+	Condition     *string    `db:"condition" json:"condition,omitempty"`
+	MinQuantity   *int       `db:"min_quantity" json:"minQuantity,omitempty"`
+	NearLatitude  *float64   `db:"near_latitude" json:"nearLatitude,omitempty"`
+	NearLongitude *float64   `db:"near_longitude" json:"nearLongitude,omitempty"`
+	RadiusKm      *float64   `db:"radius_km" json:"radiusKm,omitempty"`
+	Delivery      string     `db:"delivery" json:"delivery"`
+	LastDigestAt  *time.Time `db:"last_digest_at" json:"lastDigestAt,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updatedAt"`
 }
 
 type Notification struct {
@@ -117,11 +464,14 @@ type BuyerParticipant struct {
 }
 
 type OfferSummary struct {
-	ID           int64   `json:"id"`
-	PriceAmount  float64 `json:"priceAmount"`
-	CurrencyCode string  `json:"currencyCode"`
-	Message      *string `json:"message,omitempty"`
-	Status       string  `json:"status"`
+	ID           int64    `json:"id"`
+	PriceAmount  float64  `json:"priceAmount"`
+	CurrencyCode string   `json:"currencyCode"`
+	Message      *string  `json:"message,omitempty"`
+	Quantity     int      `json:"quantity"`
+	UnitPrice    *float64 `json:"unitPrice,omitempty"`
+	Status       string   `json:"status"`
+	OfferTerms
 }
 
 type User struct {
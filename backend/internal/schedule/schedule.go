@@ -0,0 +1,168 @@
+// Package schedule parses recurrence rules for recurring lots: the aliases
+// daily, weekly and monthly, or a five-field cron expression
+// (minute hour day-of-month month day-of-week) evaluated in UTC.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var aliases = map[string]string{
+	"daily":    "0 9 * * *",
+	"weekly":   "0 9 * * 1",
+	"monthly":  "0 9 1 * *",
+	"@daily":   "0 9 * * *",
+	"@weekly":  "0 9 * * 1",
+	"@monthly": "0 9 1 * *",
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Schedule is a parsed recurrence rule.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a "*" day field; cron matches either day
+	// field when both are restricted.
+	domAny, dowAny bool
+}
+
+// Parse accepts an alias or a five-field cron expression.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if expr, ok := aliases[spec]; ok {
+		spec = expr
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return Schedule{}, errors.New("recurrence must be daily, weekly, monthly or a cron expression with five fields")
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, err
+		}
+		sets[i] = set
+	}
+	return Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepExpr, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rangeExpr != "*" {
+			fromExpr, toExpr, isRange := strings.Cut(rangeExpr, "-")
+			from, err := strconv.Atoi(fromExpr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", rangeExpr, f.name)
+			}
+			lo, hi = from, from
+			if isRange {
+				to, err := strconv.Atoi(toExpr)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", rangeExpr, f.name)
+				}
+				hi = to
+			} else if hasStep {
+				hi = f.max
+			}
+		}
+		// Sunday may be written as 7.
+		if f.name == "day of week" && hi == 7 {
+			if lo == 7 {
+				lo, hi = 0, 0
+			} else {
+				hi = 6
+				set |= 1
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s field must be between %d and %d", f.name, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first run strictly after the given time, or the zero time
+// when the rule never fires (such as 31 February).
+func (s Schedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	// Five years covers every satisfiable combination, leap days included.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
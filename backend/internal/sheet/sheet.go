@@ -0,0 +1,73 @@
+// Package sheet reads tabular uploads (CSV and XLSX) into rows of strings.
+package sheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported file format, expected CSV or XLSX")
+
+// Read parses data as XLSX when it is a zip archive or the file name ends in
+// .xlsx, and as CSV otherwise. CSV files may use comma or semicolon
+// separators. Trailing empty rows are dropped.
+func Read(filename string, data []byte) ([][]string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ext == ".xlsx" || bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		rows, err := readXLSX(data)
+		if err != nil {
+			return nil, err
+		}
+		return trimRows(rows), nil
+	case ext == ".csv" || ext == ".txt" || ext == "":
+		return readCSV(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if firstLine, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return trimRows(rows), nil
+}
+
+func trimRows(rows [][]string) [][]string {
+	for len(rows) > 0 && IsBlank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
+
+// IsBlank reports whether every cell of the row is empty.
+func IsBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// excelEpoch is day zero of the 1900 date system, shifted to absorb the
+// spreadsheet's fictitious 29 February 1900.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// SerialTime converts a spreadsheet date serial number to a UTC time.
+func SerialTime(serial float64) time.Time {
+	return excelEpoch.Add(time.Duration(serial * 24 * float64(time.Hour)).Round(time.Second))
+}
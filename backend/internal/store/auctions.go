@@ -0,0 +1,325 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lotbuy-backend/internal/models"
+)
+
+var (
+	ErrAuctionClosed  = errors.New("the auction has ended")
+	ErrAuctionRunning = errors.New("the auction is still running")
+	ErrBidTooHigh     = errors.New("bid does not undercut the lowest bid by the minimum decrement")
+	ErrBiddingClosed  = errors.New("the bid deadline has passed")
+	ErrOffersSealed   = errors.New("offers are sealed until the bid deadline")
+	// ErrNotAuctionWinner is returned when accepting a bid other than the
+	// winning one while that still stands.
+	ErrNotAuctionWinner = errors.New("only the winning bid can be accepted")
+	// ErrBidTermsLocked is returned when changing the terms bids are made in
+	// while bidding on the lot is open.
+	ErrBidTermsLocked = errors.New("currencyCode, quantity and budgetAmount cannot change while bidding is open")
+)
+
+// bidEpsilon absorbs rounding when comparing money amounts.
+const bidEpsilon = 0.005
+
+// checkLotBid enforces the rules of the lot's mode on a new or revised offer
+// of amount, locking the lot so concurrent bids are ordered. Standard lots
+// pass unchecked, and sealed-bid lots until their deadline. On auctions, a
+// bid placed within the extension window of the end pushes the end back to a
+// full window from now.
+func checkLotBid(ctx context.Context, tx *sqlx.Tx, requestID int64, amount float64) error {
+	var req models.Request
+	if err := tx.QueryRowxContext(ctx, `
+        SELECT id, mode, auction_ends_at, auction_min_decrement, auction_extension_minutes, auction_closed_at,
+               sealed_until
+        FROM requests WHERE id = $1 FOR UPDATE
+    `, requestID).StructScan(&req); err != nil {
+		return err
+	}
+	now := time.Now()
+	if req.IsSealed() && !req.OffersSealed(now) {
+		return ErrBiddingClosed
+	}
+	if !req.IsAuction() {
+		return nil
+	}
+	if req.AuctionClosedAt != nil || req.AuctionEndsAt == nil || !req.AuctionEndsAt.After(now) {
+		return ErrAuctionClosed
+	}
+
+	var lowest sql.NullFloat64
+	if err := tx.QueryRowxContext(ctx,
+		`SELECT MIN(price_amount) FROM offers WHERE request_id = $1 AND status = 'pending'`,
+		requestID,
+	).Scan(&lowest); err != nil {
+		return err
+	}
+	if lowest.Valid {
+		decrement := 0.0
+		if req.AuctionMinDecrement != nil {
+			decrement = *req.AuctionMinDecrement
+		}
+		if amount > lowest.Float64-decrement+bidEpsilon {
+			return ErrBidTooHigh
+		}
+	}
+
+	if req.AuctionExtensionMinutes != nil && *req.AuctionExtensionMinutes > 0 {
+		window := time.Duration(*req.AuctionExtensionMinutes) * time.Minute
+		if req.AuctionEndsAt.Sub(now) < window {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE requests SET auction_ends_at = $2, updated_at = NOW() WHERE id = $1`,
+				requestID, now.Add(window),
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetAuctionState summarizes the bids on an auction lot without revealing the
+// bidders. viewerID, when set, fills in the viewer's own bid.
+func (s *Store) GetAuctionState(ctx context.Context, req *models.Request, viewerID *int64) (*models.AuctionState, error) {
+	state := models.AuctionState{
+		RequestID:     req.ID,
+		Mode:          req.Mode,
+		CurrencyCode:  req.CurrencyCode,
+		EndsAt:        req.AuctionEndsAt,
+		ClosedAt:      req.AuctionClosedAt,
+		Award:         "buyer",
+		WinnerOfferID: req.AuctionWinnerOfferID,
+	}
+	if req.AuctionMinDecrement != nil {
+		state.MinDecrement = *req.AuctionMinDecrement
+	}
+	if req.AuctionExtensionMinutes != nil {
+		state.ExtensionMinutes = *req.AuctionExtensionMinutes
+	}
+	if req.AuctionAward != nil {
+		state.Award = *req.AuctionAward
+	}
+
+	var leaderID sql.NullInt64
+	var lowest sql.NullFloat64
+	var count int
+	if err := s.db.QueryRowxContext(ctx, `
+        SELECT COUNT(*), MIN(price_amount),
+               (SELECT id FROM offers WHERE request_id = $1 AND status IN ('pending', 'accepted')
+                ORDER BY price_amount, created_at LIMIT 1)
+        FROM offers WHERE request_id = $1 AND status IN ('pending', 'accepted')
+    `, req.ID).Scan(&count, &lowest, &leaderID); err != nil {
+		return nil, err
+	}
+	state.BidCount = &count
+	if lowest.Valid {
+		state.LowestBid = &lowest.Float64
+		if state.ClosedAt == nil {
+			next := lowest.Float64 - state.MinDecrement
+			state.MaxNextBid = &next
+		}
+	}
+
+	if viewerID != nil {
+		var own struct {
+			ID          int64   `db:"id"`
+			PriceAmount float64 `db:"price_amount"`
+		}
+		err := s.db.QueryRowxContext(ctx, `
+            SELECT id, price_amount FROM offers
+            WHERE request_id = $1 AND seller_user_id = $2 AND status IN ('pending', 'accepted')
+            ORDER BY price_amount LIMIT 1
+        `, req.ID, *viewerID).StructScan(&own)
+		switch {
+		case err == nil:
+			state.YourOfferID = &own.ID
+			state.YourBid = &own.PriceAmount
+			state.Leading = leaderID.Valid && leaderID.Int64 == own.ID
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+	}
+	return &state, nil
+}
+
+// winningBidSQL formats the winning bid w of an auction for notifications.
+const winningBidSQL = `(w.price_amount::text || ' ' || w.currency_code)`
+
+// awardAuction opens the deal on the winning bid of an auction being closed
+// in tx. A failure is rolled back to before the attempt and reported to the
+// buyer, who can still accept the bid by hand.
+func awardAuction(ctx context.Context, tx *sqlx.Tx, requestID, winnerID int64) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT award_auction`); err != nil {
+		return err
+	}
+	offer, err := lockPendingOffer(ctx, tx, winnerID)
+	var deal *models.Deal
+	if err == nil {
+		deal, err = createDeal(ctx, tx, offer, false)
+	}
+	if err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT award_auction`); rollbackErr != nil {
+			return rollbackErr
+		}
+		return notifyAuctionClosed(ctx, tx, requestID, false, "auction.award_failed", "Аукцион завершён",
+			`'Лучшая ставка по лоту ' || r.title || ': ' || `+winningBidSQL+` || '. Сделку не удалось открыть автоматически (' || $4::text || '), подтвердите победителя сами'`,
+			err.Error())
+	}
+
+	if err := notifyAuctionClosed(ctx, tx, requestID, false, "auction.won", "Аукцион завершён",
+		`'Победила ставка ' || `+winningBidSQL+` || ' по лоту ' || r.title || '; сделка #' || $4::text || ' открыта'`,
+		strconv.FormatInt(deal.ID, 10)); err != nil {
+		return err
+	}
+	return notifyAuctionClosed(ctx, tx, requestID, true, "auction.won", "Ваша ставка победила",
+		`'Ваша ставка ' || `+winningBidSQL+` || ' победила в аукционе по лоту ' || r.title`, "")
+}
+
+// notifyAuctionClosed notifies the buyer of an auction lot, or the seller of
+// its winning bid when toSeller is set. body is an SQL expression over the
+// lot r and its winning bid w, which may refer to detail as $4. $4 is
+// compared in the WHERE clause too, so its type is known when body ignores it.
+func notifyAuctionClosed(ctx context.Context, tx *sqlx.Tx, requestID int64, toSeller bool, notificationType, title, body, detail string) error {
+	recipient := "r.buyer_user_id"
+	if toSeller {
+		recipient = "w.seller_user_id"
+	}
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO notifications (user_id, type, title, body, metadata)
+        SELECT `+recipient+`, $2, $3, `+body+`,
+               jsonb_strip_nulls(jsonb_build_object('requestId', r.id, 'offerId', w.id))
+        FROM requests r
+        LEFT JOIN offers w ON w.id = r.auction_winner_offer_id
+        WHERE r.id = $1 AND `+recipient+` IS NOT NULL AND $4::text IS NOT NULL
+    `, requestID, notificationType, title, detail)
+	return err
+}
+
+// RevealSealedOffers notifies the buyers of sealed-bid lots whose deadline
+// has passed that their offers are now visible, once per lot, and returns
+// how many lots were revealed.
+func (s *Store) RevealSealedOffers(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := s.db.QueryRowxContext(ctx, `
+        WITH revealed AS (
+            UPDATE requests
+            SET sealed_revealed_at = NOW()
+            WHERE mode = 'sealed_bid' AND sealed_revealed_at IS NULL AND sealed_until <= $1
+              AND status <> 'draft' AND deleted_at IS NULL
+            RETURNING id, title, buyer_user_id
+        ), notified AS (
+            INSERT INTO notifications (user_id, type, title, body, metadata)
+            SELECT r.buyer_user_id, 'offers.revealed', 'Предложения вскрыты',
+                   'Срок подачи предложений по лоту «' || r.title || '» истёк, получено предложений: ' ||
+                       (SELECT COUNT(*) FROM offers o WHERE o.request_id = r.id AND o.status IN ('pending', 'backup')),
+                   jsonb_build_object('requestId', r.id)
+            FROM revealed r
+            WHERE r.buyer_user_id IS NOT NULL
+        )
+        SELECT COUNT(*) FROM revealed
+    `, now).Scan(&count)
+	return count, err
+}
+
+// ListDueAuctions returns open auction lots whose end has passed.
+func (s *Store) ListDueAuctions(ctx context.Context, now time.Time) ([]models.Request, error) {
+	query := `SELECT ` + requestColumns + ` FROM requests
+        WHERE mode = 'reverse_auction' AND status = 'open' AND deleted_at IS NULL
+          AND auction_closed_at IS NULL AND auction_ends_at <= $1
+        ORDER BY auction_ends_at`
+
+	rows, err := s.db.QueryxContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.Request
+	for rows.Next() {
+		var req models.Request
+		if err := rows.StructScan(&req); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// CloseAuction ends a due auction and records the lowest bid that is still
+// valid, earliest first on ties, as the winner, then tells the buyer and the
+// winning seller. On lots awarded automatically the deal is opened in the
+// same transaction; if that fails the auction still closes and the buyer is
+// asked to accept the winner themselves. It fails with ErrAuctionRunning if a
+// late bid has extended the auction in the meantime.
+func (s *Store) CloseAuction(ctx context.Context, requestID int64) (*models.Request, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Lock the lot first so the winner is picked from every bid committed
+	// before the auction closed.
+	var due bool
+	if err := tx.QueryRowxContext(ctx, `
+        SELECT COALESCE(mode = 'reverse_auction' AND auction_closed_at IS NULL AND auction_ends_at <= NOW(), false)
+        FROM requests WHERE id = $1 FOR UPDATE
+    `, requestID).Scan(&due); err != nil {
+		return nil, err
+	}
+	if !due {
+		return nil, ErrAuctionRunning
+	}
+
+	var req models.Request
+	if err := tx.QueryRowxContext(ctx, `
+        UPDATE requests
+        SET auction_closed_at = NOW(), updated_at = NOW(),
+            auction_winner_offer_id = (
+                SELECT id FROM offers WHERE request_id = requests.id AND status = 'pending'
+                  AND (valid_until IS NULL OR valid_until > NOW())
+                ORDER BY price_amount, created_at LIMIT 1
+            )
+        WHERE id = $1
+        RETURNING `+requestColumns,
+		requestID,
+	).StructScan(&req); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.AuctionWinnerOfferID == nil:
+		err = notifyAuctionClosed(ctx, tx, req.ID, false, "auction.ended", "Аукцион завершён",
+			`'Аукцион по лоту ' || r.title || ' завершился без ставок'`, "")
+	case req.AuctionAward != nil && *req.AuctionAward == "auto":
+		err = awardAuction(ctx, tx, req.ID, *req.AuctionWinnerOfferID)
+	default:
+		err = notifyAuctionClosed(ctx, tx, req.ID, false, "auction.ended", "Аукцион завершён",
+			`'Лучшая ставка по лоту ' || r.title || ': ' || `+winningBidSQL+` || '. Подтвердите победителя'`, "")
+		if err == nil {
+			err = notifyAuctionClosed(ctx, tx, req.ID, true, "auction.leading", "Ваша ставка лучшая",
+				`'Ваша ставка ' || `+winningBidSQL+` || ' лучшая в аукционе по лоту ' || r.title || '; ожидайте подтверждения покупателя'`, "")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return &req, nil
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+
+	"lotbuy-backend/internal/models"
+)
+
+// ListCategories returns every category ordered for display. The taxonomy is
+// small, so callers build the tree in memory.
+func (s *Store) ListCategories(ctx context.Context) ([]models.Category, error) {
+	query := `SELECT id, parent_id, slug, names, attribute_schema, position
+              FROM categories ORDER BY parent_id NULLS FIRST, position ASC, slug ASC`
+
+	rows, err := s.db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
@@ -26,21 +26,21 @@ func (s *Store) GetUserStats(ctx context.Context, userID int64) (UserStats, erro
 	var stats UserStats
 
 	if err := s.db.QueryRowxContext(ctx,
-		`SELECT COUNT(*) FROM requests WHERE buyer_user_id = $1 AND status IN ('open','in_progress')`,
+		`SELECT COUNT(*) FROM requests WHERE buyer_user_id = $1 AND status IN ('open','in_progress') AND deleted_at IS NULL`,
 		userID,
 	).Scan(&stats.ActiveLots); err != nil {
 		return stats, err
 	}
 
 	if err := s.db.QueryRowxContext(ctx,
-		`SELECT COUNT(*) FROM offers o INNER JOIN requests r ON r.id = o.request_id WHERE r.buyer_user_id = $1 AND o.status = 'pending'`,
+		`SELECT COUNT(*) FROM offers o INNER JOIN requests r ON r.id = o.request_id WHERE r.buyer_user_id = $1 AND o.status = 'pending' AND r.deleted_at IS NULL`,
 		userID,
 	).Scan(&stats.PendingOffers); err != nil {
 		return stats, err
 	}
 
 	if err := s.db.QueryRowxContext(ctx,
-		`SELECT COUNT(*) FROM deals d INNER JOIN requests r ON r.id = d.request_id WHERE r.buyer_user_id = $1 AND d.status <> 'completed'`,
+		`SELECT COUNT(*) FROM deals d INNER JOIN requests r ON r.id = d.request_id WHERE r.buyer_user_id = $1 AND d.status NOT IN ('completed', 'cancelled')`,
 		userID,
 	).Scan(&stats.ActiveDeals); err != nil {
 		return stats, err
@@ -81,11 +81,13 @@ func (s *Store) ListPendingOffersWithRequest(ctx context.Context, buyerID int64,
 	}
 	query := `
         SELECT o.id, o.request_id, o.seller_user_id, o.seller_name, o.seller_avatar_url, o.seller_rating,
-               o.price_amount, o.currency_code, o.message, o.status, o.created_at, o.updated_at,
+               o.price_amount, o.currency_code, o.message, o.quantity, o.unit_price,
+               o.status, o.created_at, o.updated_at,
                r.title, r.image_url
         FROM offers o
         INNER JOIN requests r ON r.id = o.request_id
-        WHERE r.buyer_user_id = $1 AND o.status = 'pending'
+        WHERE r.buyer_user_id = $1 AND o.status = 'pending' AND r.deleted_at IS NULL
+          AND NOT ` + offersSealedSQL("r") + `
         ORDER BY o.created_at DESC
         LIMIT $2
     `
@@ -111,6 +113,8 @@ func (s *Store) ListPendingOffersWithRequest(ctx context.Context, buyerID int64,
 			&offer.PriceAmount,
 			&offer.CurrencyCode,
 			&offer.Message,
+			&offer.Quantity,
+			&offer.UnitPrice,
 			&offer.Status,
 			&offer.CreatedAt,
 			&offer.UpdatedAt,
@@ -14,12 +14,25 @@ import (
 )
 
 var (
-        ErrOfferUnavailable = errors.New("offer is not available")
-        ErrRequestClosed    = errors.New("request is not accepting new deals")
-        ErrDealUnauthorized = errors.New("not authorized to update this deal")
-        ErrMilestoneDone    = errors.New("milestone already completed")
+        ErrOfferUnavailable   = errors.New("offer is not available")
+        ErrOfferExpired       = errors.New("offer has expired; ask the seller to extend it")
+        ErrRequestClosed      = errors.New("request is not accepting new deals")
+        ErrDealUnauthorized   = errors.New("not authorized to update this deal")
+        ErrMilestoneDone      = errors.New("milestone already completed")
+        ErrDealCancelled      = errors.New("deal was cancelled")
+        ErrDealNotCancellable = errors.New("deal can only be cancelled before shipment or during a dispute")
+        // ErrDealModified is returned by a conditional update when the deal
+        // changed since the caller read it.
+        ErrDealModified       = errors.New("deal was modified concurrently")
 )
 
+// DealVersion is the version of a deal as the caller read it, for making an
+// update conditional on it.
+type DealVersion struct {
+        UpdatedAt        time.Time
+        RequestUpdatedAt time.Time
+}
+
 func (s *Store) GetDeal(ctx context.Context, id int64) (*models.Deal, error) {
         query := `SELECT id, request_id, offer_id, status, total_amount, currency_code,
                      due_at, last_message_text, last_message_at,
@@ -79,6 +92,16 @@ func (s *Store) GetDealDetails(ctx context.Context, id int64) (*models.DealDetai
 		return nil, err
 	}
 
+	// The offer stops negotiating once accepted, so its latest round holds
+	// the terms the deal was made on.
+	var terms models.OfferRound
+	if err := s.db.QueryRowxContext(ctx,
+		`SELECT `+offerRoundColumns+` FROM offer_rounds WHERE offer_id = $1 AND round = $2`,
+		offer.ID, offer.Round,
+	).StructScan(&terms); err != nil {
+		return nil, err
+	}
+
 	milestones, err := s.ListDealMilestones(ctx, deal.ID)
 	if err != nil {
 		return nil, err
@@ -89,11 +112,14 @@ func (s *Store) GetDealDetails(ctx context.Context, id int64) (*models.DealDetai
                 Request: *req,
                 Offer: models.OfferSummary{
                         ID:           offer.ID,
-                        PriceAmount:  offer.PriceAmount,
+                        PriceAmount:  terms.PriceAmount,
 
-			CurrencyCode: offer.CurrencyCode,
-			Message:      offer.Message,
+			CurrencyCode: terms.CurrencyCode,
+			Message:      terms.Message,
+			Quantity:     terms.Quantity,
+			UnitPrice:    terms.UnitPrice,
 			Status:       offer.Status,
+			OfferTerms:   terms.OfferTerms,
 		},
 		Seller: models.OfferParticipant{
 			Name:   offer.SellerName,
@@ -119,7 +145,10 @@ type dealContext struct {
         Offer   models.Offer
 }
 
-func (s *Store) loadDealContext(ctx context.Context, tx *sqlx.Tx, dealID int64) (*dealContext, error) {
+// loadDealContext locks the deal and its lot and loads them with the offer.
+// When ifVersion is set and either the deal or its lot moved on since, it
+// returns ErrDealModified.
+func (s *Store) loadDealContext(ctx context.Context, tx *sqlx.Tx, dealID int64, ifVersion *DealVersion) (*dealContext, error) {
         var deal models.Deal
         if err := tx.QueryRowxContext(ctx, `SELECT id, request_id, offer_id, status, total_amount, currency_code,
                 due_at, last_message_text, last_message_at,
@@ -130,19 +159,35 @@ func (s *Store) loadDealContext(ctx context.Context, tx *sqlx.Tx, dealID int64)
                 return nil, err
         }
 
-        req, err := s.GetRequest(ctx, deal.RequestID)
-        if err != nil {
+        // The lot is locked too, so its version cannot move between the
+        // check below and the commit.
+        var req models.Request
+        if err := tx.QueryRowxContext(ctx,
+                `SELECT `+requestColumns+` FROM requests WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+                deal.RequestID,
+        ).StructScan(&req); err != nil {
                 return nil, err
         }
-        offer, err := s.GetOffer(ctx, deal.OfferID)
-        if err != nil {
+        var offer models.Offer
+        if err := tx.QueryRowxContext(ctx, `SELECT `+offerColumns+` FROM offers WHERE id = $1`, deal.OfferID).StructScan(&offer); err != nil {
                 return nil, err
         }
+        if ifVersion != nil && (!deal.UpdatedAt.Equal(ifVersion.UpdatedAt) || !req.UpdatedAt.Equal(ifVersion.RequestUpdatedAt)) {
+                return nil, ErrDealModified
+        }
+        if deal.Status == "cancelled" {
+                return nil, ErrDealCancelled
+        }
 
-        return &dealContext{Deal: deal, Request: *req, Offer: *offer}, nil
+        return &dealContext{Deal: deal, Request: req, Offer: offer}, nil
 }
 
-func (s *Store) CreateDealFromOffer(ctx context.Context, offerID int64) (*models.DealDetails, error) {
+// CreateDealFromOffer closes the negotiation of an offer on the terms of its
+// latest round. party is the side accepting those terms and must be the one
+// the round awaits; round, when set, must still be the latest. The other open
+// offers on the lot are declined, or kept as backups when keepBackups is set,
+// and their sellers are notified.
+func (s *Store) CreateDealFromOffer(ctx context.Context, offerID int64, party string, round *int, keepBackups bool) (*models.DealDetails, error) {
 	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, err
@@ -154,36 +199,76 @@ func (s *Store) CreateDealFromOffer(ctx context.Context, offerID int64) (*models
 		}
 	}()
 
-	var offer models.Offer
-	if err = tx.QueryRowxContext(ctx, `
-        SELECT id, request_id, seller_name, seller_avatar_url, seller_rating,
-               price_amount, currency_code, message, status, created_at, updated_at
-        FROM offers WHERE id = $1 FOR UPDATE
-    `, offerID).StructScan(&offer); err != nil {
+	offer, err := lockNegotiableOffer(ctx, tx, offerID, party, round)
+	if err != nil {
 		return nil, err
 	}
+	if offer.ValidUntil != nil && !offer.ValidUntil.After(time.Now()) {
+		return nil, ErrOfferExpired
+	}
+
+	deal, err := createDeal(ctx, tx, offer, keepBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+
+	return s.GetDealDetails(ctx, deal.ID)
+}
 
-	if offer.Status != "pending" {
-		return nil, ErrOfferUnavailable
+// createDeal opens a deal on the latest round of offer, which the caller has
+// locked, and settles the lot and its other offers. On auctions only the
+// standing winning bid can be accepted.
+func createDeal(ctx context.Context, tx *sqlx.Tx, offer *models.Offer, keepBackups bool) (*models.Deal, error) {
+	var terms models.OfferRound
+	if err := tx.QueryRowxContext(ctx,
+		`SELECT `+offerRoundColumns+` FROM offer_rounds WHERE offer_id = $1 AND round = $2`,
+		offer.ID, offer.Round,
+	).StructScan(&terms); err != nil {
+		return nil, err
 	}
 
 	var req models.Request
-	if err = tx.QueryRowxContext(ctx, `
+	if err := tx.QueryRowxContext(ctx, `
         SELECT id, title, description, budget_amount, currency_code, buyer_name,
-               buyer_avatar_url, buyer_rating, image_url, status, created_at, updated_at
+               buyer_avatar_url, buyer_rating, image_url, status, created_at, updated_at, deleted_at,
+               mode, auction_closed_at, auction_winner_offer_id, sealed_until
         FROM requests WHERE id = $1 FOR UPDATE
     `, offer.RequestID).StructScan(&req); err != nil {
 		return nil, err
 	}
 
-	if req.Status != "open" {
+	if req.Status != "open" || req.DeletedAt != nil {
 		return nil, ErrRequestClosed
 	}
+	if req.IsAuction() && req.AuctionClosedAt == nil {
+		return nil, ErrAuctionRunning
+	}
+	if req.IsAuction() && req.AuctionWinnerOfferID != nil && *req.AuctionWinnerOfferID != offer.ID {
+		// Other bids become eligible once the winning one falls through.
+		var winnerStands bool
+		if err := tx.QueryRowxContext(ctx, `
+            SELECT status IN ('pending', 'backup') AND (valid_until IS NULL OR valid_until > NOW())
+            FROM offers WHERE id = $1
+        `, *req.AuctionWinnerOfferID).Scan(&winnerStands); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if winnerStands {
+			return nil, ErrNotAuctionWinner
+		}
+	}
+	if req.OffersSealed(time.Now()) {
+		return nil, ErrOffersSealed
+	}
 
 	now := time.Now()
 	dueAt := now.Add(48 * time.Hour)
 	var deal models.Deal
-        if err = tx.QueryRowxContext(ctx, `
+        if err := tx.QueryRowxContext(ctx, `
         INSERT INTO deals (
             request_id, offer_id, status, total_amount, currency_code, due_at,
             last_message_text, last_message_at
@@ -197,8 +282,8 @@ func (s *Store) CreateDealFromOffer(ctx context.Context, offerID int64) (*models
                 offer.RequestID,
                 offer.ID,
                 "awaiting_shipment",
-                offer.PriceAmount,
-                offer.CurrencyCode,
+                terms.PriceAmount,
+                terms.CurrencyCode,
                 dueAt,
 		"Offer accepted. Waiting for payment.",
 		now,
@@ -206,24 +291,30 @@ func (s *Store) CreateDealFromOffer(ctx context.Context, offerID int64) (*models
 		return nil, err
 	}
 
-	if _, err = tx.ExecContext(ctx, `UPDATE offers SET status = 'accepted', updated_at = NOW() WHERE id = $1`, offer.ID); err != nil {
+	if _, err := tx.ExecContext(ctx, `UPDATE offers SET status = 'accepted', closed_at = NOW(), updated_at = NOW() WHERE id = $1`, offer.ID); err != nil {
 		return nil, err
 	}
 
-	if _, err = tx.ExecContext(ctx, `UPDATE requests SET status = 'in_progress', updated_at = NOW() WHERE id = $1`, req.ID); err != nil {
+	if _, err := tx.ExecContext(ctx, `UPDATE requests SET status = 'in_progress', updated_at = NOW() WHERE id = $1`, req.ID); err != nil {
 		return nil, err
 	}
 
-	if err = insertDefaultMilestones(ctx, tx, deal.ID, now); err != nil {
+	var err error
+	if keepBackups {
+		err = closeCompetingOffers(ctx, tx, req.ID, offer.ID, "backup", "offer.backup",
+			"Ваше предложение в резерве", "Покупатель выбрал другое предложение, но оставил ваше в резерве по лоту ")
+	} else {
+		err = closeCompetingOffers(ctx, tx, req.ID, offer.ID, "declined", "offer.declined",
+			"Предложение отклонено", "Покупатель выбрал другое предложение по лоту ")
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	if err = tx.Commit(); err != nil {
+	if err := insertDefaultMilestones(ctx, tx, deal.ID, now); err != nil {
 		return nil, err
 	}
-	committed = true
-
-	return s.GetDealDetails(ctx, deal.ID)
+	return &deal, nil
 }
 
 func insertDefaultMilestones(ctx context.Context, tx *sqlx.Tx, dealID int64, acceptedAt time.Time) error {
@@ -281,7 +372,7 @@ func participantRole(ctx *dealContext, userID int64) (isBuyer bool, isSeller boo
         return
 }
 
-func (s *Store) MarkDealShipped(ctx context.Context, dealID, userID int64) (*models.DealDetails, error) {
+func (s *Store) MarkDealShipped(ctx context.Context, dealID, userID int64, ifVersion *DealVersion) (*models.DealDetails, error) {
         tx, err := s.db.BeginTxx(ctx, nil)
         if err != nil {
                 return nil, err
@@ -293,7 +384,7 @@ func (s *Store) MarkDealShipped(ctx context.Context, dealID, userID int64) (*mod
                 }
         }()
 
-        data, err := s.loadDealContext(ctx, tx, dealID)
+        data, err := s.loadDealContext(ctx, tx, dealID, ifVersion)
         if err != nil {
                 return nil, err
         }
@@ -332,7 +423,7 @@ func (s *Store) MarkDealShipped(ctx context.Context, dealID, userID int64) (*mod
         return s.GetDealDetails(ctx, dealID)
 }
 
-func (s *Store) SubmitDealPayment(ctx context.Context, dealID, userID int64) (*models.DealDetails, error) {
+func (s *Store) SubmitDealPayment(ctx context.Context, dealID, userID int64, ifVersion *DealVersion) (*models.DealDetails, error) {
         tx, err := s.db.BeginTxx(ctx, nil)
         if err != nil {
                 return nil, err
@@ -344,7 +435,7 @@ func (s *Store) SubmitDealPayment(ctx context.Context, dealID, userID int64) (*m
                 }
         }()
 
-        data, err := s.loadDealContext(ctx, tx, dealID)
+        data, err := s.loadDealContext(ctx, tx, dealID, ifVersion)
         if err != nil {
                 return nil, err
         }
@@ -383,7 +474,7 @@ func (s *Store) SubmitDealPayment(ctx context.Context, dealID, userID int64) (*m
         return s.GetDealDetails(ctx, dealID)
 }
 
-func (s *Store) ConfirmDealCompletion(ctx context.Context, dealID, userID int64) (*models.DealDetails, error) {
+func (s *Store) ConfirmDealCompletion(ctx context.Context, dealID, userID int64, ifVersion *DealVersion) (*models.DealDetails, error) {
         tx, err := s.db.BeginTxx(ctx, nil)
         if err != nil {
                 return nil, err
@@ -395,7 +486,7 @@ func (s *Store) ConfirmDealCompletion(ctx context.Context, dealID, userID int64)
                 }
         }()
 
-        data, err := s.loadDealContext(ctx, tx, dealID)
+        data, err := s.loadDealContext(ctx, tx, dealID, ifVersion)
         if err != nil {
                 return nil, err
         }
@@ -434,6 +525,11 @@ func (s *Store) ConfirmDealCompletion(ctx context.Context, dealID, userID int64)
         if _, err := tx.ExecContext(ctx, `UPDATE offers SET status = 'completed', updated_at = NOW() WHERE id = $1`, data.Deal.OfferID); err != nil {
                 return nil, err
         }
+        // Backups are no longer needed once the deal went through.
+        if err := closeCompetingOffers(ctx, tx, data.Deal.RequestID, data.Deal.OfferID, "declined", "offer.declined",
+                "Предложение отклонено", "Сделка по лоту завершена с другим продавцом: "); err != nil {
+                return nil, err
+        }
 
         if data.Request.BuyerID != nil {
                 if _, err := tx.ExecContext(ctx, `UPDATE users SET completed_deals = completed_deals + 1 WHERE id = $1`, *data.Request.BuyerID); err != nil {
@@ -479,7 +575,7 @@ func (s *Store) completeMilestone(ctx context.Context, tx *sqlx.Tx, milestoneID
         return err
 }
 
-func (s *Store) OpenDealDispute(ctx context.Context, dealID, userID int64, reason string) (*models.DealDetails, error) {
+func (s *Store) OpenDealDispute(ctx context.Context, dealID, userID int64, reason string, ifVersion *DealVersion) (*models.DealDetails, error) {
         tx, err := s.db.BeginTxx(ctx, nil)
         if err != nil {
                 return nil, err
@@ -491,7 +587,7 @@ func (s *Store) OpenDealDispute(ctx context.Context, dealID, userID int64, reaso
                 }
         }()
 
-        data, err := s.loadDealContext(ctx, tx, dealID)
+        data, err := s.loadDealContext(ctx, tx, dealID, ifVersion)
         if err != nil {
                 return nil, err
         }
@@ -531,11 +627,102 @@ func (s *Store) OpenDealDispute(ctx context.Context, dealID, userID int64, reaso
         return s.GetDealDetails(ctx, dealID)
 }
 
+// CancelDeal calls off a deal before the goods ship, or ends a dispute that
+// way. The lot opens again and its backup offers turn pending, so the buyer
+// can accept one of them; their sellers and the other party are notified.
+func (s *Store) CancelDeal(ctx context.Context, dealID, userID int64, reason string, ifVersion *DealVersion) (*models.DealDetails, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	data, err := s.loadDealContext(ctx, tx, dealID, ifVersion)
+	if err != nil {
+		return nil, err
+	}
+	isBuyer, isSeller := participantRole(data, userID)
+	if !isBuyer && !isSeller {
+		return nil, ErrDealUnauthorized
+	}
+	if data.Deal.Status != "awaiting_shipment" && data.Deal.Status != "in_dispute" {
+		return nil, ErrDealNotCancellable
+	}
+
+	trimmed := strings.TrimSpace(reason)
+	if trimmed == "" {
+		trimmed = "Deal cancelled"
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE deals
+        SET status = 'cancelled',
+            last_message_text = $2,
+            last_message_at = $3,
+            updated_at = $3
+        WHERE id = $1`, dealID, trimmed, now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE offers SET status = 'cancelled', closed_at = $2, updated_at = $2 WHERE id = $1`, data.Deal.OfferID, now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE requests SET status = 'open', updated_at = NOW() WHERE id = $1`, data.Deal.RequestID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        WITH reopened AS (
+            UPDATE offers
+            SET status = 'pending', updated_at = NOW()
+            WHERE request_id = $1 AND status = 'backup'
+            RETURNING id, request_id, seller_user_id
+        )
+        INSERT INTO notifications (user_id, type, title, body, metadata)
+        SELECT o.seller_user_id, 'offer.reopened', 'Лот снова открыт',
+               'Сделка по лоту «' || r.title || '» сорвалась, ваше предложение из резерва снова рассматривается',
+               jsonb_build_object('offerId', o.id, 'requestId', o.request_id)
+        FROM reopened o
+        INNER JOIN requests r ON r.id = o.request_id
+        WHERE o.seller_user_id IS NOT NULL
+    `, data.Deal.RequestID); err != nil {
+		return nil, err
+	}
+
+	counterparty := data.Offer.SellerID
+	if isSeller {
+		counterparty = data.Request.BuyerID
+	}
+	if counterparty != nil {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO notifications (user_id, type, title, body, metadata)
+            VALUES ($1, 'deal.cancelled', 'Сделка отменена',
+                    'Сделка по лоту «' || $2::text || '» отменена: ' || $3::text,
+                    jsonb_build_object('dealId', $4::int, 'requestId', $5::int))
+        `, *counterparty, data.Request.Title, trimmed, dealID, data.Deal.RequestID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+
+	return s.GetDealDetails(ctx, dealID)
+}
+
 type DealFeedbackParams struct {
         DealID     int64
         ReviewerID int64
         Rating     int
         Comment    *string
+        IfVersion  *DealVersion
 }
 
 func (s *Store) AddDealFeedback(ctx context.Context, params DealFeedbackParams) (*models.DealDetails, error) {
@@ -554,7 +741,7 @@ func (s *Store) AddDealFeedback(ctx context.Context, params DealFeedbackParams)
                 }
         }()
 
-        data, err := s.loadDealContext(ctx, tx, params.DealID)
+        data, err := s.loadDealContext(ctx, tx, params.DealID, params.IfVersion)
         if err != nil {
                 return nil, err
         }
@@ -0,0 +1,248 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lotbuy-backend/internal/models"
+)
+
+// ErrNotYourTurn is returned when a party answers a round that awaits the
+// other party, or one that is no longer the latest.
+var ErrNotYourTurn = errors.New("the offer is awaiting the other party")
+
+const offerRoundColumns = `id, offer_id, round, proposed_by, user_id, price_amount, currency_code,
+        quantity, unit_price, message, created_at, ` + offerTermColumns
+
+// CounterOfferParams proposes new terms for an offer on behalf of Party
+// ("buyer" or "seller").
+type CounterOfferParams struct {
+	OfferID      int64
+	Party        string
+	UserID       int64
+	PriceAmount  float64
+	CurrencyCode string
+	Quantity     int
+	UnitPrice    *float64
+	Message      *string
+	// Round, when set, is the round being answered; the counter fails with
+	// ErrNotYourTurn if the negotiation has moved on since.
+	Round *int
+	// ValidUntil, when set, replaces the offer's validity. Only sellers
+	// bound their prices in time.
+	ValidUntil *time.Time
+	// Terms replaces the delivery, warranty and payment terms of the offer.
+	Terms models.OfferTerms
+}
+
+// otherParty returns the negotiating party opposite to party.
+func otherParty(party string) string {
+	if party == "buyer" {
+		return "seller"
+	}
+	return "buyer"
+}
+
+// insertOfferRound records the current terms of the offer as its latest round.
+func insertOfferRound(ctx context.Context, tx *sqlx.Tx, offer *models.Offer, party string, userID int64) (*models.OfferRound, error) {
+	var round models.OfferRound
+	if err := tx.QueryRowxContext(ctx, `
+        INSERT INTO offer_rounds (offer_id, round, proposed_by, user_id, price_amount, currency_code, quantity, unit_price, message,
+                                  delivery_days, shipping_cost, shipping_method, warranty_months, payment_terms, item_condition)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+        RETURNING `+offerRoundColumns,
+		offer.ID,
+		offer.Round,
+		party,
+		userID,
+		offer.PriceAmount,
+		offer.CurrencyCode,
+		offer.Quantity,
+		offer.UnitPrice,
+		offer.Message,
+		offer.DeliveryDays,
+		offer.ShippingCost,
+		offer.ShippingMethod,
+		offer.WarrantyMonths,
+		offer.PaymentTerms,
+		offer.ItemCondition,
+	).StructScan(&round); err != nil {
+		return nil, err
+	}
+	return &round, nil
+}
+
+// lockPendingOffer loads an offer for update and checks that it is still
+// open: pending, or kept as a backup after the buyer accepted another one.
+func lockPendingOffer(ctx context.Context, tx *sqlx.Tx, offerID int64) (*models.Offer, error) {
+	var offer models.Offer
+	if err := tx.QueryRowxContext(ctx, `SELECT `+offerColumns+` FROM offers WHERE id = $1 FOR UPDATE`, offerID).StructScan(&offer); err != nil {
+		return nil, err
+	}
+	if offer.Status != "pending" && offer.Status != "backup" {
+		return nil, ErrOfferUnavailable
+	}
+	return &offer, nil
+}
+
+// closeCompetingOffers settles the other open offers on a lot in one
+// statement and notifies their sellers: status is "declined" when the buyer
+// has settled on a deal, or "backup" when they keep the offers in reserve.
+func closeCompetingOffers(ctx context.Context, tx *sqlx.Tx, requestID, acceptedID int64, status, notificationType, title, body string) error {
+	_, err := tx.ExecContext(ctx, `
+        WITH closed AS (
+            UPDATE offers
+            SET status = $3::text,
+                closed_at = CASE WHEN $3::text = 'declined' THEN NOW() END,
+                updated_at = NOW()
+            WHERE request_id = $1 AND id <> $2 AND status IN ('pending', 'backup')
+            RETURNING id, request_id, seller_user_id
+        )
+        INSERT INTO notifications (user_id, type, title, body, metadata)
+        SELECT c.seller_user_id, $4, $5, $6 || r.title,
+               jsonb_build_object('offerId', c.id, 'requestId', c.request_id)
+        FROM closed c
+        INNER JOIN requests r ON r.id = c.request_id
+        WHERE c.seller_user_id IS NOT NULL
+    `, requestID, acceptedID, status, notificationType, title, body)
+	return err
+}
+
+// lockNegotiableOffer loads a pending offer for update and checks that it
+// awaits party.
+func lockNegotiableOffer(ctx context.Context, tx *sqlx.Tx, offerID int64, party string, round *int) (*models.Offer, error) {
+	offer, err := lockPendingOffer(ctx, tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Awaiting != party || (round != nil && *round != offer.Round) {
+		return nil, ErrNotYourTurn
+	}
+	return offer, nil
+}
+
+// CounterOffer adds a round with new terms and hands the turn to the other
+// party. The offer row takes over the new terms.
+func (s *Store) CounterOffer(ctx context.Context, params CounterOfferParams) (*models.Offer, *models.OfferRound, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := lockNegotiableOffer(ctx, tx, params.OfferID, params.Party, params.Round); err != nil {
+		return nil, nil, err
+	}
+
+	var offer models.Offer
+	if err := tx.QueryRowxContext(ctx, `
+        UPDATE offers
+        SET price_amount = $2, currency_code = $3, quantity = $4, unit_price = $5,
+            round = round + 1, awaiting = $6, valid_until = COALESCE($7, valid_until),
+            delivery_days = $8, shipping_cost = $9, shipping_method = $10, warranty_months = $11,
+            payment_terms = $12, item_condition = $13, updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+offerColumns,
+		params.OfferID,
+		params.PriceAmount,
+		strings.ToUpper(params.CurrencyCode),
+		params.Quantity,
+		params.UnitPrice,
+		otherParty(params.Party),
+		params.ValidUntil,
+		params.Terms.DeliveryDays,
+		params.Terms.ShippingCost,
+		params.Terms.ShippingMethod,
+		params.Terms.WarrantyMonths,
+		params.Terms.PaymentTerms,
+		params.Terms.ItemCondition,
+	).StructScan(&offer); err != nil {
+		return nil, nil, err
+	}
+	// The offer keeps the seller's original message; each round carries its
+	// own.
+	roundOffer := offer
+	roundOffer.Message = params.Message
+	round, err := insertOfferRound(ctx, tx, &roundOffer, params.Party, params.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	committed = true
+	return &offer, round, nil
+}
+
+// DeclineOffer ends the negotiation on behalf of the seller when the buyer's
+// counter awaits them.
+func (s *Store) DeclineOffer(ctx context.Context, offerID int64, round *int) (*models.Offer, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := lockNegotiableOffer(ctx, tx, offerID, "seller", round); err != nil {
+		return nil, err
+	}
+	offer, err := closeOffer(ctx, tx, offerID, "declined", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return offer, nil
+}
+
+// closeOffer moves a locked pending offer to a final status.
+func closeOffer(ctx context.Context, tx *sqlx.Tx, offerID int64, status string, reason *string) (*models.Offer, error) {
+	var offer models.Offer
+	if err := tx.QueryRowxContext(ctx, `
+        UPDATE offers
+        SET status = $2, rejection_reason = $3, closed_at = NOW(), updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+offerColumns,
+		offerID, status, reason,
+	).StructScan(&offer); err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// ListOfferRounds returns the negotiation of an offer, first round first.
+func (s *Store) ListOfferRounds(ctx context.Context, offerID int64) ([]models.OfferRound, error) {
+	rows, err := s.db.QueryxContext(ctx, `SELECT `+offerRoundColumns+` FROM offer_rounds WHERE offer_id = $1 ORDER BY round ASC`, offerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rounds := []models.OfferRound{}
+	for rows.Next() {
+		var round models.OfferRound
+		if err := rows.StructScan(&round); err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, round)
+	}
+	return rounds, rows.Err()
+}
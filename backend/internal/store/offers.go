@@ -2,11 +2,52 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"lotbuy-backend/internal/models"
 )
 
+// offerColumns lists the columns scanned into models.Offer. An offer is
+// outdated once the lot has a material revision newer than the one it was
+// made against.
+var offerColumns = `id, request_id, seller_user_id, seller_name, seller_avatar_url, seller_rating,
+        price_amount, currency_code, message, quantity, unit_price, status, created_at, updated_at,
+        request_revision, ` + offerOutdatedSQL("offers") + ` AS request_outdated, round, awaiting,
+        rejection_reason, closed_at, valid_until, ` + offerTermColumns
+
+// offerTermColumns lists the columns scanned into models.OfferTerms.
+const offerTermColumns = `delivery_days, shipping_cost, shipping_method, warranty_months,
+        payment_terms, item_condition`
+
+// offerOutdatedSQL reports whether the offer aliased as table was made before
+// the latest material revision of its lot.
+func offerOutdatedSQL(table string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM request_revisions rr
+        WHERE rr.request_id = %[1]s.request_id AND rr.material AND rr.revision > %[1]s.request_revision)`, table)
+}
+
+// offersSealedSQL reports whether the offers on the lot aliased as table are
+// still sealed.
+func offersSealedSQL(table string) string {
+	return fmt.Sprintf(`(%[1]s.mode = 'sealed_bid' AND (%[1]s.sealed_until IS NULL OR %[1]s.sealed_until > NOW()))`, table)
+}
+
+// offerHiddenSQL reports whether the offer aliased as table is hidden from the
+// user given by the SQL expression viewer. Offers on sealed-bid lots are only
+// visible to their seller, and to the buyer once the lot is unsealed.
+func offerHiddenSQL(table, viewer string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM requests sr WHERE sr.id = %[1]s.request_id AND sr.mode = 'sealed_bid'
+            AND %[1]s.seller_user_id IS DISTINCT FROM %[2]s
+            AND (sr.buyer_user_id IS DISTINCT FROM %[2]s OR `+offersSealedSQL("sr")+`))`, table, viewer)
+}
+
+// ErrActiveOfferExists is returned when a seller offers on a lot where they
+// already have a pending or backup offer; they revise that one instead.
+var ErrActiveOfferExists = errors.New("seller already has an active offer on this lot")
+
 type CreateOfferParams struct {
 	RequestID    int64
 	SellerID     int64
@@ -16,20 +57,55 @@ type CreateOfferParams struct {
 	PriceAmount  float64
 	CurrencyCode string
 	Message      *string
+	Quantity     int
+	UnitPrice    *float64
+	ValidUntil   *time.Time
+	Terms        models.OfferTerms
 }
 
+// CreateOffer stores the offer together with its first negotiation round. The
+// lot must be open, and the seller must not have another active offer on it.
 func (s *Store) CreateOffer(ctx context.Context, params CreateOfferParams) (*models.Offer, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
 	query := `
         INSERT INTO offers (
             request_id, seller_user_id, seller_name, seller_avatar_url, seller_rating,
-            price_amount, currency_code, message
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-        RETURNING id, request_id, seller_user_id, seller_name, seller_avatar_url, seller_rating,
-                  price_amount, currency_code, message, status, created_at, updated_at
-    `
+            price_amount, currency_code, message, quantity, unit_price, valid_until,
+            ` + offerTermColumns + `, request_revision
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+            (SELECT revision FROM requests WHERE id = $1))
+        RETURNING ` + offerColumns
+
+	if err := checkLotBid(ctx, tx, params.RequestID, params.PriceAmount); err != nil {
+		return nil, err
+	}
+	var open, exists bool
+	if err := tx.QueryRowxContext(ctx, `
+        SELECT r.status = 'open' AND r.deleted_at IS NULL,
+               EXISTS (SELECT 1 FROM offers WHERE request_id = r.id AND seller_user_id = $2 AND status IN ('pending', 'backup'))
+        FROM requests r WHERE r.id = $1
+    `, params.RequestID, params.SellerID).Scan(&open, &exists); err != nil {
+		return nil, err
+	}
+	if !open {
+		return nil, ErrRequestClosed
+	}
+	if exists {
+		return nil, ErrActiveOfferExists
+	}
 
 	var offer models.Offer
-	if err := s.db.QueryRowxContext(ctx, query,
+	if err := tx.QueryRowxContext(ctx, query,
 		params.RequestID,
 		params.SellerID,
 		params.SellerName,
@@ -38,17 +114,33 @@ func (s *Store) CreateOffer(ctx context.Context, params CreateOfferParams) (*mod
 		params.PriceAmount,
 		strings.ToUpper(params.CurrencyCode),
 		params.Message,
+		params.Quantity,
+		params.UnitPrice,
+		params.ValidUntil,
+		params.Terms.DeliveryDays,
+		params.Terms.ShippingCost,
+		params.Terms.ShippingMethod,
+		params.Terms.WarrantyMonths,
+		params.Terms.PaymentTerms,
+		params.Terms.ItemCondition,
 	).StructScan(&offer); err != nil {
 		return nil, err
 	}
 
+	if _, err := insertOfferRound(ctx, tx, &offer, "seller", params.SellerID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+
 	return &offer, nil
 }
 
 func (s *Store) GetOffer(ctx context.Context, id int64) (*models.Offer, error) {
-	query := `SELECT id, request_id, seller_user_id, seller_name, seller_avatar_url, seller_rating,
-                     price_amount, currency_code, message, status, created_at, updated_at
-              FROM offers WHERE id = $1`
+	query := `SELECT ` + offerColumns + ` FROM offers WHERE id = $1`
 
 	var offer models.Offer
 	if err := s.db.QueryRowxContext(ctx, query, id).StructScan(&offer); err != nil {
@@ -57,12 +149,39 @@ func (s *Store) GetOffer(ctx context.Context, id int64) (*models.Offer, error) {
 	return &offer, nil
 }
 
-func (s *Store) ListOffersByRequest(ctx context.Context, requestID int64) ([]models.Offer, error) {
-	query := `SELECT id, request_id, seller_user_id, seller_name, seller_avatar_url, seller_rating,
-                     price_amount, currency_code, message, status, created_at, updated_at
-              FROM offers WHERE request_id = $1 ORDER BY created_at DESC`
+// GetVisibleOffer returns the offer if the user may see it, and
+// sql.ErrNoRows otherwise.
+func (s *Store) GetVisibleOffer(ctx context.Context, id, userID int64) (*models.Offer, error) {
+	query := `SELECT ` + offerColumns + ` FROM offers WHERE id = $1 AND NOT ` + offerHiddenSQL("offers", "$2::int")
 
-	rows, err := s.db.QueryxContext(ctx, query, requestID)
+	var offer models.Offer
+	if err := s.db.QueryRowxContext(ctx, query, id, userID).StructScan(&offer); err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// GetActiveOffer returns the seller's pending or backup offer on the lot, and
+// sql.ErrNoRows if they have none.
+func (s *Store) GetActiveOffer(ctx context.Context, requestID, sellerID int64) (*models.Offer, error) {
+	query := `SELECT ` + offerColumns + ` FROM offers
+        WHERE request_id = $1 AND seller_user_id = $2 AND status IN ('pending', 'backup')`
+
+	var offer models.Offer
+	if err := s.db.QueryRowxContext(ctx, query, requestID, sellerID).StructScan(&offer); err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// ListOffersByRequest returns the offers on a lot that viewerID, or an
+// anonymous caller when nil, may see.
+func (s *Store) ListOffersByRequest(ctx context.Context, requestID int64, viewerID *int64) ([]models.Offer, error) {
+	query := `SELECT ` + offerColumns + ` FROM offers
+        WHERE request_id = $1 AND NOT ` + offerHiddenSQL("offers", "$2::int") + `
+        ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryxContext(ctx, query, requestID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +198,269 @@ func (s *Store) ListOffersByRequest(ctx context.Context, requestID int64) ([]mod
 	return offers, rows.Err()
 }
 
+// EditOfferParams revises the terms of a seller's pending offer.
+type EditOfferParams struct {
+	OfferID      int64
+	SellerID     int64
+	PriceAmount  float64
+	CurrencyCode string
+	Quantity     int
+	UnitPrice    *float64
+	Message      *string
+	// ValidUntil, when set, replaces the offer's validity.
+	ValidUntil *time.Time
+	Terms      models.OfferTerms
+}
+
+// EditOffer replaces the terms of the seller's latest round. It is only
+// allowed while the offer awaits the buyer; a buyer's counter is answered
+// with a counter instead. The revision is recorded as a new round and the
+// offer is brought up to date with the lot.
+func (s *Store) EditOffer(ctx context.Context, params EditOfferParams) (*models.Offer, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	current, err := lockNegotiableOffer(ctx, tx, params.OfferID, "buyer", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLotBid(ctx, tx, current.RequestID, params.PriceAmount); err != nil {
+		return nil, err
+	}
+
+	var offer models.Offer
+	if err := tx.QueryRowxContext(ctx, `
+        UPDATE offers
+        SET price_amount = $2, currency_code = $3, quantity = $4, unit_price = $5, message = $6,
+            valid_until = COALESCE($7, valid_until), delivery_days = $8, shipping_cost = $9,
+            shipping_method = $10, warranty_months = $11, payment_terms = $12, item_condition = $13,
+            round = round + 1, updated_at = NOW(),
+            request_revision = (SELECT revision FROM requests WHERE id = offers.request_id)
+        WHERE id = $1
+        RETURNING `+offerColumns,
+		params.OfferID,
+		params.PriceAmount,
+		strings.ToUpper(params.CurrencyCode),
+		params.Quantity,
+		params.UnitPrice,
+		params.Message,
+		params.ValidUntil,
+		params.Terms.DeliveryDays,
+		params.Terms.ShippingCost,
+		params.Terms.ShippingMethod,
+		params.Terms.WarrantyMonths,
+		params.Terms.PaymentTerms,
+		params.Terms.ItemCondition,
+	).StructScan(&offer); err != nil {
+		return nil, err
+	}
+	if _, err := insertOfferRound(ctx, tx, &offer, "seller", params.SellerID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return &offer, nil
+}
+
+// WithdrawOffer lets the seller take back a pending offer.
+func (s *Store) WithdrawOffer(ctx context.Context, offerID int64) (*models.Offer, error) {
+	return s.closePendingOffer(ctx, offerID, "withdrawn", nil)
+}
+
+// RejectOffer lets the buyer turn down a pending offer, optionally saying why.
+func (s *Store) RejectOffer(ctx context.Context, offerID int64, reason *string) (*models.Offer, error) {
+	return s.closePendingOffer(ctx, offerID, "rejected", reason)
+}
+
+func (s *Store) closePendingOffer(ctx context.Context, offerID int64, status string, reason *string) (*models.Offer, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := lockPendingOffer(ctx, tx, offerID); err != nil {
+		return nil, err
+	}
+	offer, err := closeOffer(ctx, tx, offerID, status, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return offer, nil
+}
+
+// ExtendOffer moves the validity of the seller's offer to until. An expired
+// offer becomes pending again as long as its lot is still open and the seller
+// has not made another offer on it since.
+func (s *Store) ExtendOffer(ctx context.Context, offerID int64, until time.Time) (*models.Offer, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var current models.Offer
+	if err := tx.QueryRowxContext(ctx, `SELECT `+offerColumns+` FROM offers WHERE id = $1 FOR UPDATE`, offerID).StructScan(&current); err != nil {
+		return nil, err
+	}
+	switch current.Status {
+	case "pending", "backup":
+	case "expired":
+		var open, exists bool
+		if err := tx.QueryRowxContext(ctx, `
+            SELECT r.status = 'open' AND r.deleted_at IS NULL,
+                   EXISTS (SELECT 1 FROM offers WHERE request_id = r.id AND seller_user_id = $2 AND status IN ('pending', 'backup'))
+            FROM requests r WHERE r.id = $1
+        `, current.RequestID, current.SellerID).Scan(&open, &exists); err != nil {
+			return nil, err
+		}
+		if !open {
+			return nil, ErrRequestClosed
+		}
+		if exists {
+			return nil, ErrActiveOfferExists
+		}
+	default:
+		return nil, ErrOfferUnavailable
+	}
+
+	var offer models.Offer
+	if err := tx.QueryRowxContext(ctx, `
+        UPDATE offers
+        SET valid_until = $2,
+            status = CASE WHEN status = 'expired' THEN 'pending' ELSE status END,
+            closed_at = CASE WHEN status = 'expired' THEN NULL ELSE closed_at END,
+            updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+offerColumns, offerID, until).StructScan(&offer); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return &offer, nil
+}
+
+// ExpireOffers marks open offers past their validity as expired and notifies
+// both the seller and the buyer of each. It returns how many expired.
+func (s *Store) ExpireOffers(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := s.db.QueryRowxContext(ctx, `
+        WITH expired AS (
+            UPDATE offers
+            SET status = 'expired', closed_at = NOW(), updated_at = NOW()
+            WHERE status IN ('pending', 'backup') AND valid_until <= $1
+            RETURNING id, request_id, seller_user_id, seller_name
+        ), recipients AS (
+            SELECT e.seller_user_id AS user_id, 'Срок действия вашего предложения по лоту «' || r.title || '» истёк' AS body,
+                   e.id, e.request_id
+            FROM expired e INNER JOIN requests r ON r.id = e.request_id
+            WHERE e.seller_user_id IS NOT NULL
+            UNION ALL
+            SELECT r.buyer_user_id, 'Истёк срок действия предложения ' || e.seller_name || ' по лоту «' || r.title || '»',
+                   e.id, e.request_id
+            FROM expired e INNER JOIN requests r ON r.id = e.request_id
+            WHERE r.buyer_user_id IS NOT NULL AND NOT `+offersSealedSQL("r")+`
+        ), notified AS (
+            INSERT INTO notifications (user_id, type, title, body, metadata)
+            SELECT user_id, 'offer.expired', 'Предложение истекло', body,
+                   jsonb_build_object('offerId', id, 'requestId', request_id)
+            FROM recipients
+        )
+        SELECT COUNT(*) FROM expired
+    `, now).Scan(&count)
+	return count, err
+}
+
+// ListComparableOffers returns the open offers on a lot with their sellers'
+// completed deals and average rating.
+func (s *Store) ListComparableOffers(ctx context.Context, requestID int64) ([]models.ComparableOffer, error) {
+	query := `
+        SELECT o.*,
+               COALESCE(u.completed_deals, 0) AS seller_completed_deals,
+               CASE WHEN u.rating_count > 0 THEN u.rating_total::float8 / u.rating_count END AS seller_average_rating
+        FROM (
+            SELECT ` + offerColumns + ` FROM offers
+            WHERE request_id = $1 AND status IN ('pending', 'backup')
+              AND (valid_until IS NULL OR valid_until > NOW())
+              AND NOT EXISTS (SELECT 1 FROM requests r WHERE r.id = offers.request_id AND ` + offersSealedSQL("r") + `)
+        ) o
+        LEFT JOIN users u ON u.id = o.seller_user_id
+        ORDER BY o.created_at
+    `
+
+	rows, err := s.db.QueryxContext(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offers []models.ComparableOffer
+	for rows.Next() {
+		var o models.ComparableOffer
+		if err := rows.StructScan(&o); err != nil {
+			return nil, err
+		}
+		offers = append(offers, o)
+	}
+	return offers, rows.Err()
+}
+
+// GetOfferScoreWeights returns the comparison weights the user saved, or
+// sql.ErrNoRows when they never saved any.
+func (s *Store) GetOfferScoreWeights(ctx context.Context, userID int64) (*models.OfferScoreWeights, error) {
+	var weights models.OfferScoreWeights
+	if err := s.db.QueryRowxContext(ctx, `
+        SELECT price_weight, delivery_weight, rating_weight, experience_weight
+        FROM offer_score_weights WHERE user_id = $1
+    `, userID).StructScan(&weights); err != nil {
+		return nil, err
+	}
+	return &weights, nil
+}
+
+// SaveOfferScoreWeights stores the user's comparison weights, replacing any
+// saved before.
+func (s *Store) SaveOfferScoreWeights(ctx context.Context, userID int64, weights models.OfferScoreWeights) error {
+	_, err := s.db.ExecContext(ctx, `
+        INSERT INTO offer_score_weights (user_id, price_weight, delivery_weight, rating_weight, experience_weight)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id) DO UPDATE
+        SET price_weight = EXCLUDED.price_weight, delivery_weight = EXCLUDED.delivery_weight,
+            rating_weight = EXCLUDED.rating_weight, experience_weight = EXCLUDED.experience_weight,
+            updated_at = NOW()
+    `, userID, weights.Price, weights.Delivery, weights.Rating, weights.Experience)
+	return err
+}
+
 func (s *Store) UpdateOfferStatus(ctx context.Context, id int64, status string) error {
 	_, err := s.db.ExecContext(ctx, `UPDATE offers SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
 	return err
@@ -87,10 +469,15 @@ func (s *Store) UpdateOfferStatus(ctx context.Context, id int64, status string)
 func (s *Store) ListOffersForBuyer(ctx context.Context, buyerID int64, status string, limit int) ([]models.Offer, error) {
 	query := `
         SELECT o.id, o.request_id, o.seller_user_id, o.seller_name, o.seller_avatar_url, o.seller_rating,
-               o.price_amount, o.currency_code, o.message, o.status, o.created_at, o.updated_at
+               o.price_amount, o.currency_code, o.message, o.quantity, o.unit_price,
+               o.status, o.created_at, o.updated_at, o.request_revision,
+               ` + offerOutdatedSQL("o") + ` AS request_outdated, o.round, o.awaiting,
+               o.rejection_reason, o.closed_at, o.valid_until, o.delivery_days, o.shipping_cost,
+               o.shipping_method, o.warranty_months, o.payment_terms, o.item_condition
         FROM offers o
         INNER JOIN requests r ON r.id = o.request_id
-        WHERE r.buyer_user_id = $1 AND ($2 = '' OR o.status = $2)
+        WHERE r.buyer_user_id = $1 AND ($2 = '' OR o.status = $2) AND r.deleted_at IS NULL
+          AND NOT ` + offersSealedSQL("r") + `
         ORDER BY o.created_at DESC
         LIMIT $3
     `
@@ -113,9 +500,8 @@ func (s *Store) ListOffersForBuyer(ctx context.Context, buyerID int64, status st
 }
 
 func (s *Store) ListOffersBySeller(ctx context.Context, sellerID int64, status string) ([]models.Offer, error) {
-	query := `SELECT id, request_id, seller_user_id, seller_name, seller_avatar_url, seller_rating,
-                     price_amount, currency_code, message, status, created_at, updated_at
-              FROM offers WHERE seller_user_id = $1 AND ($2 = '' OR status = $2)
+	query := `SELECT ` + offerColumns + ` FROM offers
+              WHERE seller_user_id = $1 AND ($2 = '' OR status = $2)
               ORDER BY created_at DESC`
 
 	rows, err := s.db.QueryxContext(ctx, query, sellerID, status)
@@ -134,3 +520,15 @@ func (s *Store) ListOffersBySeller(ctx context.Context, sellerID int64, status s
 	}
 	return offers, rows.Err()
 }
+
+// NotifyPendingOfferSellers sends the same notification to every seller with
+// a pending offer on the lot.
+func (s *Store) NotifyPendingOfferSellers(ctx context.Context, requestID int64, notificationType, title string, body *string, metadata []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+        INSERT INTO notifications (user_id, type, title, body, metadata)
+        SELECT DISTINCT seller_user_id, $2, $3, $4, $5::jsonb
+        FROM offers
+        WHERE request_id = $1 AND status = 'pending' AND seller_user_id IS NOT NULL
+    `, requestID, notificationType, title, body, metadata)
+	return err
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"lotbuy-backend/internal/models"
+)
+
+// CreateOrganization creates an organization with ownerID as its owner.
+func (s *Store) CreateOrganization(ctx context.Context, name string, ownerID int64) (*models.Organization, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var org models.Organization
+	if err := tx.QueryRowxContext(ctx, `
+        INSERT INTO organizations (name) VALUES ($1)
+        RETURNING id, name, 'owner' AS role, created_at
+    `, name).StructScan(&org); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, 'owner')`,
+		org.ID, ownerID,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return &org, nil
+}
+
+func (s *Store) ListUserOrganizations(ctx context.Context, userID int64) ([]models.Organization, error) {
+	query := `
+        SELECT o.id, o.name, m.role, o.created_at
+        FROM organizations o
+        INNER JOIN organization_members m ON m.organization_id = o.id
+        WHERE m.user_id = $1
+        ORDER BY o.name ASC
+    `
+
+	rows, err := s.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.StructScan(&org); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// GetOrganizationRole returns the user's role in the organization, or an
+// empty string when they are not a member.
+func (s *Store) GetOrganizationRole(ctx context.Context, orgID, userID int64) (string, error) {
+	var role string
+	err := s.db.QueryRowxContext(ctx,
+		`SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+func (s *Store) ListOrganizationMembers(ctx context.Context, orgID int64) ([]models.OrganizationMember, error) {
+	query := `
+        SELECT m.user_id, u.email, u.full_name, m.role, m.created_at
+        FROM organization_members m
+        INNER JOIN users u ON u.id = m.user_id
+        WHERE m.organization_id = $1
+        ORDER BY m.created_at ASC
+    `
+
+	rows, err := s.db.QueryxContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrganizationMember{}
+	for rows.Next() {
+		var member models.OrganizationMember
+		if err := rows.StructScan(&member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// AddOrganizationMember adds the registered user with the given email as a
+// member. It returns sql.ErrNoRows when no such user exists.
+func (s *Store) AddOrganizationMember(ctx context.Context, orgID int64, email string) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := s.db.QueryRowxContext(ctx, `
+        WITH added AS (
+            INSERT INTO organization_members (organization_id, user_id)
+            SELECT $1, id FROM users WHERE email = lower($2)
+            ON CONFLICT (organization_id, user_id) DO UPDATE SET role = organization_members.role
+            RETURNING user_id, role, created_at
+        )
+        SELECT added.user_id, u.email, u.full_name, added.role, added.created_at
+        FROM added INNER JOIN users u ON u.id = added.user_id
+    `, orgID, email).StructScan(&member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *Store) RemoveOrganizationMember(ctx context.Context, orgID, userID int64) error {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"lotbuy-backend/internal/models"
+)
+
+const MaxRequestImages = 10
+
+var (
+	ErrTooManyImages     = errors.New("request already has the maximum number of images")
+	ErrInvalidImageOrder = errors.New("image order must list every image of the request exactly once")
+	ErrGalleryHasImages  = errors.New("imageUrl cannot be cleared while the request has gallery images; delete them instead")
+)
+
+type AddRequestImageParams struct {
+	RequestID int64
+	URL       string
+	AltText   *string
+	Width     *int
+	Height    *int
+	IsCover   bool
+}
+
+type UpdateRequestImageParams struct {
+	RequestID int64
+	ImageID   int64
+	AltText   *sql.NullString
+	IsCover   *bool
+}
+
+func (s *Store) ListRequestImages(ctx context.Context, requestID int64) ([]models.RequestImage, error) {
+	query := `SELECT id, request_id, url, alt_text, width, height, position, is_cover, created_at
+              FROM request_images WHERE request_id = $1 ORDER BY position ASC, id ASC`
+
+	rows, err := s.db.QueryxContext(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []models.RequestImage{}
+	for rows.Next() {
+		var img models.RequestImage
+		if err := rows.StructScan(&img); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// AddRequestImage appends an image to the end of the gallery. The first image
+// of a request always becomes its cover.
+func (s *Store) AddRequestImage(ctx context.Context, params AddRequestImageParams) (*models.RequestImage, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Lock the parent row so concurrent uploads get distinct positions.
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM requests WHERE id = $1 FOR UPDATE`, params.RequestID); err != nil {
+		return nil, err
+	}
+
+	var count, nextPosition int
+	if err := tx.QueryRowxContext(ctx,
+		`SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM request_images WHERE request_id = $1`,
+		params.RequestID,
+	).Scan(&count, &nextPosition); err != nil {
+		return nil, err
+	}
+	if count >= MaxRequestImages {
+		return nil, ErrTooManyImages
+	}
+
+	isCover := params.IsCover || count == 0
+	if isCover {
+		if _, err := tx.ExecContext(ctx, `UPDATE request_images SET is_cover = FALSE WHERE request_id = $1 AND is_cover`, params.RequestID); err != nil {
+			return nil, err
+		}
+	}
+
+	var img models.RequestImage
+	if err := tx.QueryRowxContext(ctx, `
+        INSERT INTO request_images (request_id, url, alt_text, width, height, position, is_cover)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, request_id, url, alt_text, width, height, position, is_cover, created_at
+    `, params.RequestID, params.URL, params.AltText, params.Width, params.Height, nextPosition, isCover).StructScan(&img); err != nil {
+		return nil, err
+	}
+
+	if err := touchRequest(ctx, tx, params.RequestID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return &img, nil
+}
+
+func (s *Store) UpdateRequestImage(ctx context.Context, params UpdateRequestImageParams) (*models.RequestImage, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if params.IsCover != nil && *params.IsCover {
+		if _, err := tx.ExecContext(ctx, `UPDATE request_images SET is_cover = FALSE WHERE request_id = $1 AND is_cover AND id <> $2`, params.RequestID, params.ImageID); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE request_images
+        SET alt_text = CASE WHEN $3 THEN $4 ELSE alt_text END,
+            is_cover = COALESCE($5, is_cover)
+        WHERE id = $1 AND request_id = $2
+    `, params.ImageID, params.RequestID, params.AltText != nil, params.AltText, params.IsCover)
+	if err != nil {
+		return nil, err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	// Un-flagging the cover hands it to the first image in the gallery,
+	// which may be this very image.
+	if err := ensureCoverImage(ctx, tx, params.RequestID); err != nil {
+		return nil, err
+	}
+
+	if err := touchRequest(ctx, tx, params.RequestID); err != nil {
+		return nil, err
+	}
+
+	var img models.RequestImage
+	if err := tx.QueryRowxContext(ctx, `
+        SELECT id, request_id, url, alt_text, width, height, position, is_cover, created_at
+        FROM request_images WHERE id = $1
+    `, params.ImageID).StructScan(&img); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return &img, nil
+}
+
+// ReorderRequestImages assigns positions following imageIDs, which must be a
+// permutation of the request's current images.
+func (s *Store) ReorderRequestImages(ctx context.Context, requestID int64, imageIDs []int64) ([]models.RequestImage, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var existing []int64
+	if err := tx.SelectContext(ctx, &existing, `SELECT id FROM request_images WHERE request_id = $1 FOR UPDATE`, requestID); err != nil {
+		return nil, err
+	}
+	if len(existing) != len(imageIDs) {
+		return nil, ErrInvalidImageOrder
+	}
+	known := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+	for _, id := range imageIDs {
+		if !known[id] {
+			return nil, ErrInvalidImageOrder
+		}
+		delete(known, id)
+	}
+
+	for position, id := range imageIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE request_images SET position = $1 WHERE id = $2`, position, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := touchRequest(ctx, tx, requestID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+
+	return s.ListRequestImages(ctx, requestID)
+}
+
+// DeleteRequestImage removes an image and promotes the first remaining image
+// to cover when the deleted one was the cover.
+func (s *Store) DeleteRequestImage(ctx context.Context, requestID, imageID int64) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM request_images WHERE id = $1 AND request_id = $2`, imageID, requestID)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := ensureCoverImage(ctx, tx, requestID); err != nil {
+		return err
+	}
+	if err := touchRequest(ctx, tx, requestID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// touchRequest moves the lot's updated_at so its ETag changes with the
+// gallery.
+func touchRequest(ctx context.Context, tx *sqlx.Tx, requestID int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE requests SET updated_at = NOW() WHERE id = $1`, requestID)
+	return err
+}
+
+// setCoverImageURL keeps the gallery in step with a new imageUrl: an image
+// already in the gallery becomes the cover, and any other URL is appended as
+// the new cover. The caller holds the lot's row lock.
+func setCoverImageURL(ctx context.Context, tx *sqlx.Tx, requestID int64, url sql.NullString) error {
+	var count, nextPosition int
+	if err := tx.QueryRowxContext(ctx,
+		`SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM request_images WHERE request_id = $1`,
+		requestID,
+	).Scan(&count, &nextPosition); err != nil {
+		return err
+	}
+	if !url.Valid {
+		if count > 0 {
+			return ErrGalleryHasImages
+		}
+		return nil
+	}
+
+	var existingID int64
+	err := tx.QueryRowxContext(ctx,
+		`SELECT id FROM request_images WHERE request_id = $1 AND url = $2 ORDER BY position LIMIT 1`,
+		requestID, url.String,
+	).Scan(&existingID)
+	switch {
+	case err == nil:
+		_, err = tx.ExecContext(ctx,
+			`UPDATE request_images SET is_cover = (id = $2) WHERE request_id = $1`, requestID, existingID)
+		return err
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	if count >= MaxRequestImages {
+		return ErrTooManyImages
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE request_images SET is_cover = FALSE WHERE request_id = $1 AND is_cover`, requestID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO request_images (request_id, url, position, is_cover) VALUES ($1, $2, $3, TRUE)
+    `, requestID, url.String, nextPosition)
+	return err
+}
+
+func ensureCoverImage(ctx context.Context, tx *sqlx.Tx, requestID int64) error {
+	_, err := tx.ExecContext(ctx, `
+        UPDATE request_images SET is_cover = TRUE
+        WHERE id = (
+            SELECT id FROM request_images WHERE request_id = $1
+            ORDER BY position ASC, id ASC LIMIT 1
+        )
+          AND NOT EXISTS (SELECT 1 FROM request_images WHERE request_id = $1 AND is_cover)
+    `, requestID)
+	return err
+}
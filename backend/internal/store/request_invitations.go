@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"lotbuy-backend/internal/models"
+)
+
+const invitationColumns = `id, request_id, email, user_id, created_at`
+
+// invitedSQL matches an invitation to the lot for the user, by account or by
+// the email they were invited with.
+func invitedSQL(requestID, userID, email string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM request_invitations inv
+        WHERE inv.request_id = %s AND (inv.user_id = %s OR inv.email = lower(%s)))`, requestID, userID, email)
+}
+
+// CreateRequestInvitations invites sellers by email and by account. Emails
+// that belong to registered users are linked to them; repeated invitations
+// are ignored. It returns the invitations that were newly created.
+func (s *Store) CreateRequestInvitations(ctx context.Context, requestID int64, emails []string, userIDs []int64) ([]models.RequestInvitation, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	invitations := []models.RequestInvitation{}
+	insert := func(query string, arg interface{}) error {
+		var invitation models.RequestInvitation
+		err := tx.QueryRowxContext(ctx, query, requestID, arg).StructScan(&invitation)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		invitations = append(invitations, invitation)
+		return nil
+	}
+
+	for _, email := range emails {
+		if err := insert(`
+            INSERT INTO request_invitations (request_id, email, user_id)
+            VALUES ($1, lower($2), (SELECT id FROM users WHERE lower(email) = lower($2)))
+            ON CONFLICT (request_id, email) DO NOTHING
+            RETURNING `+invitationColumns, strings.TrimSpace(email)); err != nil {
+			return nil, err
+		}
+	}
+	for _, userID := range userIDs {
+		if err := insert(`
+            INSERT INTO request_invitations (request_id, email, user_id)
+            SELECT $1, lower(email), id FROM users WHERE id = $2
+            ON CONFLICT (request_id, email) DO NOTHING
+            RETURNING `+invitationColumns, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return invitations, nil
+}
+
+func (s *Store) ListRequestInvitations(ctx context.Context, requestID int64) ([]models.RequestInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM request_invitations WHERE request_id = $1 ORDER BY created_at ASC, id ASC`
+
+	rows, err := s.db.QueryxContext(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.RequestInvitation{}
+	for rows.Next() {
+		var invitation models.RequestInvitation
+		if err := rows.StructScan(&invitation); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *Store) DeleteRequestInvitation(ctx context.Context, requestID, invitationID int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM request_invitations WHERE id = $1 AND request_id = $2`, invitationID, requestID)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+func (s *Store) IsRequestInvitee(ctx context.Context, requestID, userID int64, email string) (bool, error) {
+	var invited bool
+	err := s.db.QueryRowxContext(ctx, `SELECT `+invitedSQL("$1", "$2", "$3"), requestID, userID, email).Scan(&invited)
+	return invited, err
+}
+
+// SetRequestShareToken replaces the share link token of a lot; nil revokes
+// the link.
+func (s *Store) SetRequestShareToken(ctx context.Context, requestID int64, token *string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE requests SET share_token = $2, updated_at = NOW() WHERE id = $1`, requestID, token)
+	return err
+}
+
+func (s *Store) GetRequestShareToken(ctx context.Context, requestID int64) (*string, error) {
+	var token *string
+	if err := s.db.QueryRowxContext(ctx, `SELECT share_token FROM requests WHERE id = $1`, requestID).Scan(&token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+
+	"lotbuy-backend/internal/models"
+)
+
+// materialRequestFields are the lot fields whose change may invalidate offers
+// already made against the lot.
+var materialRequestFields = map[string]bool{
+	"description":  true,
+	"budgetAmount": true,
+	"currencyCode": true,
+	"deadlineAt":   true,
+	"condition":    true,
+	"quantity":     true,
+	"unit":         true,
+	"category":     true,
+	"subcategory":  true,
+	"attributes":   true,
+}
+
+// diffRequests returns the editable fields that differ between two versions
+// of a lot, keyed by their API names.
+func diffRequests(before, after *models.Request) map[string]models.FieldChange {
+	fields := []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"title", before.Title, after.Title},
+		{"description", before.Description, after.Description},
+		{"budgetAmount", before.BudgetAmount, after.BudgetAmount},
+		{"currencyCode", before.CurrencyCode, after.CurrencyCode},
+		{"imageUrl", before.ImageURL, after.ImageURL},
+		{"category", before.Category, after.Category},
+		{"subcategory", before.Subcategory, after.Subcategory},
+		{"locationCity", before.LocationCity, after.LocationCity},
+		{"locationRegion", before.LocationRegion, after.LocationRegion},
+		{"locationCountry", before.LocationCountry, after.LocationCountry},
+		{"latitude", before.Latitude, after.Latitude},
+		{"longitude", before.Longitude, after.Longitude},
+		{"deadlineAt", before.DeadlineAt, after.DeadlineAt},
+		{"condition", before.Condition, after.Condition},
+		{"quantity", before.Quantity, after.Quantity},
+		{"unit", before.Unit, after.Unit},
+		{"visibility", before.Visibility, after.Visibility},
+		{"offerVisibility", before.OfferVisibility, after.OfferVisibility},
+		{"attributes", before.Attributes, after.Attributes},
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.from, f.to) {
+			changes[f.name] = models.FieldChange{From: f.from, To: f.to}
+		}
+	}
+	return changes
+}
+
+// recordRequestRevision stores the difference between two versions of a lot
+// and bumps its revision. It returns nil when nothing changed.
+func recordRequestRevision(ctx context.Context, tx *sqlx.Tx, before, after *models.Request, changedBy int64) (*models.RequestRevision, error) {
+	changes := diffRequests(before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	material := false
+	for field := range changes {
+		if materialRequestFields[field] {
+			material = true
+			break
+		}
+	}
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	var revision models.RequestRevision
+	if err := tx.QueryRowxContext(ctx, `
+        WITH bumped AS (
+            UPDATE requests SET revision = revision + 1 WHERE id = $1 RETURNING revision
+        )
+        INSERT INTO request_revisions (request_id, revision, changed_by, changes, material)
+        SELECT $1, bumped.revision, $2, $3, $4 FROM bumped
+        RETURNING id, request_id, revision, changed_by, NULL::text AS changed_by_name, changes, material, created_at
+    `, after.ID, changedBy, payload, material).StructScan(&revision); err != nil {
+		return nil, err
+	}
+	after.Revision = revision.Revision
+	return &revision, nil
+}
+
+func (s *Store) ListRequestRevisions(ctx context.Context, requestID int64) ([]models.RequestRevision, error) {
+	query := `
+        SELECT rr.id, rr.request_id, rr.revision, rr.changed_by, u.full_name AS changed_by_name,
+               rr.changes, rr.material, rr.created_at
+        FROM request_revisions rr
+        LEFT JOIN users u ON u.id = rr.changed_by
+        WHERE rr.request_id = $1
+        ORDER BY rr.revision DESC
+    `
+
+	rows, err := s.db.QueryxContext(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.RequestRevision{}
+	for rows.Next() {
+		var revision models.RequestRevision
+		if err := rows.StructScan(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
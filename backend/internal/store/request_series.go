@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lotbuy-backend/internal/models"
+)
+
+const seriesColumns = `id, user_id, template_id, name, recurrence, deadline_days, active, next_run_at, last_run_at, last_error, created_at, updated_at`
+
+type CreateRequestSeriesParams struct {
+	UserID       int64
+	TemplateID   int64
+	Name         string
+	Recurrence   string
+	DeadlineDays *int
+	NextRunAt    *time.Time
+}
+
+type UpdateRequestSeriesParams struct {
+	ID           int64
+	UserID       int64
+	TemplateID   *int64
+	Name         *string
+	Recurrence   *string
+	DeadlineDays *sql.NullInt64
+	Active       *bool
+	// NextRunAt is written whenever the recurrence or the active flag
+	// changes, since both move the next occurrence.
+	NextRunAt *time.Time
+}
+
+func (s *Store) CreateRequestSeries(ctx context.Context, params CreateRequestSeriesParams) (*models.RequestSeries, error) {
+	query := `
+        INSERT INTO request_series (user_id, template_id, name, recurrence, deadline_days, next_run_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING ` + seriesColumns
+
+	var series models.RequestSeries
+	if err := s.db.QueryRowxContext(ctx, query,
+		params.UserID,
+		params.TemplateID,
+		params.Name,
+		params.Recurrence,
+		params.DeadlineDays,
+		params.NextRunAt,
+	).StructScan(&series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (s *Store) GetRequestSeries(ctx context.Context, id int64) (*models.RequestSeries, error) {
+	var series models.RequestSeries
+	if err := s.db.QueryRowxContext(ctx, `SELECT `+seriesColumns+` FROM request_series WHERE id = $1`, id).StructScan(&series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (s *Store) ListRequestSeries(ctx context.Context, userID int64) ([]models.RequestSeries, error) {
+	return s.querySeries(ctx, `SELECT `+seriesColumns+` FROM request_series WHERE user_id = $1 ORDER BY name ASC, id ASC`, userID)
+}
+
+// ListDueSeries returns active series whose next occurrence is at or before
+// now, oldest first.
+func (s *Store) ListDueSeries(ctx context.Context, now time.Time, limit int) ([]models.RequestSeries, error) {
+	query := `
+        SELECT ` + seriesColumns + ` FROM request_series
+        WHERE active AND next_run_at IS NOT NULL AND next_run_at <= $1
+        ORDER BY next_run_at ASC
+        LIMIT $2
+    `
+	return s.querySeries(ctx, query, now, limit)
+}
+
+func (s *Store) querySeries(ctx context.Context, query string, args ...interface{}) ([]models.RequestSeries, error) {
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.RequestSeries{}
+	for rows.Next() {
+		var series models.RequestSeries
+		if err := rows.StructScan(&series); err != nil {
+			return nil, err
+		}
+		list = append(list, series)
+	}
+	return list, rows.Err()
+}
+
+// ClaimSeriesRun moves a due series on to its next occurrence. It succeeds
+// only if next_run_at still holds the value the caller read, so concurrent
+// runners never publish the same occurrence twice. A nil next deactivates the
+// series.
+func (s *Store) ClaimSeriesRun(ctx context.Context, id int64, due time.Time, next *time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE request_series
+        SET next_run_at = $3::timestamptz, active = $3::timestamptz IS NOT NULL, last_run_at = NOW(), last_error = NULL, updated_at = NOW()
+        WHERE id = $1 AND active AND next_run_at = $2
+    `, id, due, next)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// FailSeriesRun records why the latest occurrence could not be published and
+// tells the owner of the series.
+func (s *Store) FailSeriesRun(ctx context.Context, id int64, message string) error {
+	_, err := s.db.ExecContext(ctx, `
+        WITH failed AS (
+            UPDATE request_series
+            SET last_error = $2, updated_at = NOW()
+            WHERE id = $1
+            RETURNING id, user_id, name
+        )
+        INSERT INTO notifications (user_id, type, title, body, metadata)
+        SELECT f.user_id, 'series.failed', 'Ошибка повторяющегося лота',
+               'Не удалось опубликовать лот серии ' || f.name || ': ' || $2,
+               jsonb_build_object('seriesId', f.id)
+        FROM failed f
+    `, id, message)
+	return err
+}
+
+func (s *Store) UpdateRequestSeries(ctx context.Context, params UpdateRequestSeriesParams) (*models.RequestSeries, error) {
+	setClauses := make([]string, 0, 7)
+	args := make([]interface{}, 0, 9)
+	idx := 1
+
+	if params.TemplateID != nil {
+		setClauses = append(setClauses, fmt.Sprintf("template_id = $%d", idx))
+		args = append(args, *params.TemplateID)
+		idx++
+	}
+	if params.Name != nil {
+		setClauses = append(setClauses, fmt.Sprintf("name = $%d", idx))
+		args = append(args, *params.Name)
+		idx++
+	}
+	if params.Recurrence != nil {
+		setClauses = append(setClauses, fmt.Sprintf("recurrence = $%d", idx))
+		args = append(args, *params.Recurrence)
+		idx++
+	}
+	if params.DeadlineDays != nil {
+		setClauses = append(setClauses, fmt.Sprintf("deadline_days = $%d", idx))
+		args = append(args, *params.DeadlineDays)
+		idx++
+	}
+	if params.Active != nil {
+		setClauses = append(setClauses, fmt.Sprintf("active = $%d", idx))
+		args = append(args, *params.Active)
+		idx++
+	}
+	if params.Recurrence != nil || params.Active != nil {
+		setClauses = append(setClauses, fmt.Sprintf("next_run_at = $%d", idx))
+		args = append(args, params.NextRunAt)
+		idx++
+	}
+	setClauses = append(setClauses, "updated_at = NOW()")
+	args = append(args, params.ID, params.UserID)
+
+	query := fmt.Sprintf(`UPDATE request_series SET %s WHERE id = $%d AND user_id = $%d RETURNING %s`,
+		strings.Join(setClauses, ", "), idx, idx+1, seriesColumns)
+
+	var series models.RequestSeries
+	if err := s.db.QueryRowxContext(ctx, query, args...).StructScan(&series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// DeleteRequestSeries stops the series. Lots it already published stay and
+// lose their link to it.
+func (s *Store) DeleteRequestSeries(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM request_series WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// ListSeriesOccurrences returns every lot of a series, oldest first, with its
+// offer count, best offer and the latest deal it ended in.
+func (s *Store) ListSeriesOccurrences(ctx context.Context, seriesID int64) ([]models.SeriesOccurrence, error) {
+	query := `
+        SELECT r.id AS request_id, r.title, r.status, r.budget_amount, r.currency_code, r.quantity,
+               COALESCE(r.publish_at, r.created_at) AS published_at,
+               (SELECT COUNT(*) FROM offers o WHERE o.request_id = r.id) AS offer_count,
+               (SELECT MIN(o.price_amount) FROM offers o WHERE o.request_id = r.id AND NOT ` + offersSealedSQL("r") + `) AS best_offer,
+               d.id AS deal_id, d.status AS deal_status, d.total_amount AS deal_amount,
+               d.currency_code AS deal_currency, d.unit_price AS deal_unit_price,
+               d.created_at AS deal_created_at
+        FROM requests r
+        LEFT JOIN LATERAL (
+            SELECT d.id, d.status, d.total_amount, d.currency_code, d.created_at,
+                   COALESCE(o.unit_price, ROUND(d.total_amount / NULLIF(o.quantity, 0), 2)) AS unit_price
+            FROM deals d
+            JOIN offers o ON o.id = d.offer_id
+            WHERE d.request_id = r.id
+            ORDER BY d.created_at DESC
+            LIMIT 1
+        ) d ON TRUE
+        WHERE r.series_id = $1 AND r.deleted_at IS NULL
+        ORDER BY published_at ASC, r.id ASC
+    `
+
+	rows, err := s.db.QueryxContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	occurrences := []models.SeriesOccurrence{}
+	for rows.Next() {
+		var occurrence models.SeriesOccurrence
+		if err := rows.StructScan(&occurrence); err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, rows.Err()
+}
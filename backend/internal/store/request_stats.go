@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"lotbuy-backend/internal/currency"
+	"lotbuy-backend/internal/models"
+)
+
+// viewDedupWindow is how long repeat views of a lot by the same viewer are
+// counted once.
+const viewDedupWindow = 24 * time.Hour
+
+// RecordRequestView counts a view of the lot unless the same viewer already
+// viewed it within the dedup window. It reports whether the view was counted.
+func (s *Store) RecordRequestView(ctx context.Context, requestID int64, viewerKey string) (bool, error) {
+	query := `
+        WITH counted AS (
+            INSERT INTO request_views (request_id, viewer_key, viewed_at)
+            VALUES ($1, $2, NOW())
+            ON CONFLICT (request_id, viewer_key) DO UPDATE SET viewed_at = NOW()
+            WHERE request_views.viewed_at < NOW() - $3 * INTERVAL '1 second'
+            RETURNING request_id
+        ), daily AS (
+            INSERT INTO request_view_days (request_id, day, views)
+            SELECT request_id, CURRENT_DATE, 1 FROM counted
+            ON CONFLICT (request_id, day) DO UPDATE SET views = request_view_days.views + 1
+        )
+        UPDATE requests SET view_count = view_count + 1
+        WHERE id IN (SELECT request_id FROM counted)
+    `
+	res, err := s.db.ExecContext(ctx, query, requestID, viewerKey, viewDedupWindow.Seconds())
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// PruneRequestViews drops dedup rows that have left the window; the counts
+// they contributed stay on the lot and in the daily totals.
+func (s *Store) PruneRequestViews(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM request_views WHERE viewed_at < $1`, now.Add(-viewDedupWindow))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// statsOfferPrice is the price of one active offer as it enters the medians.
+type statsOfferPrice struct {
+	PriceAmount  float64  `db:"price_amount"`
+	UnitPrice    *float64 `db:"unit_price"`
+	CurrencyCode string   `db:"currency_code"`
+}
+
+// GetRequestStats aggregates the views, watchers and offers of a lot, with
+// daily views for the last days. Only active offers are counted, and sealed
+// offers are left out of every offer figure until the lot is unsealed. Median
+// prices are in the lot currency; offers in a currency without a known rate
+// are skipped.
+func (s *Store) GetRequestStats(ctx context.Context, requestID int64, days int) (*models.RequestStats, error) {
+	query := `
+        SELECT r.id AS request_id,
+               r.currency_code,
+               r.view_count AS views,
+               (SELECT COUNT(*) FROM request_watchers rw WHERE rw.request_id = r.id) AS watchers,
+               COUNT(o.id) FILTER (WHERE o.status IN ('pending', 'backup', 'accepted')) AS offer_count,
+               MIN(o.created_at) AS first_offer_at,
+               EXTRACT(EPOCH FROM MIN(o.created_at) - COALESCE(r.publish_at, r.created_at))::BIGINT AS time_to_first_offer_seconds
+        FROM requests r
+        LEFT JOIN offers o ON o.request_id = r.id AND NOT ` + offersSealedSQL("r") + `
+        WHERE r.id = $1
+        GROUP BY r.id
+    `
+
+	var stats models.RequestStats
+	if err := s.db.QueryRowxContext(ctx, query, requestID).StructScan(&stats); err != nil {
+		return nil, err
+	}
+
+	if stats.CurrencyCode != nil {
+		var prices []statsOfferPrice
+		if err := s.db.SelectContext(ctx, &prices, `
+            SELECT o.price_amount, COALESCE(o.unit_price, o.price_amount / NULLIF(o.quantity, 0)) AS unit_price, o.currency_code
+            FROM offers o
+            JOIN requests r ON r.id = o.request_id
+            WHERE o.request_id = $1 AND o.status IN ('pending', 'backup', 'accepted')
+              AND NOT `+offersSealedSQL("r")+`
+        `, requestID); err != nil {
+			return nil, err
+		}
+		totals := make([]float64, 0, len(prices))
+		units := make([]float64, 0, len(prices))
+		for _, p := range prices {
+			total, ok := currency.Convert(p.PriceAmount, p.CurrencyCode, *stats.CurrencyCode)
+			if !ok {
+				continue
+			}
+			totals = append(totals, total)
+			if p.UnitPrice != nil {
+				unit, _ := currency.Convert(*p.UnitPrice, p.CurrencyCode, *stats.CurrencyCode)
+				units = append(units, unit)
+			}
+		}
+		stats.MedianOfferPrice = median(totals)
+		stats.MedianUnitPrice = median(units)
+	}
+
+	rows, err := s.db.QueryxContext(ctx, `
+        SELECT to_char(day, 'YYYY-MM-DD') AS day, views FROM request_view_days
+        WHERE request_id = $1 AND day > CURRENT_DATE - $2::int
+        ORDER BY day ASC
+    `, requestID, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats.ViewsByDay = []models.DailyViews{}
+	for rows.Next() {
+		var day models.DailyViews
+		if err := rows.StructScan(&day); err != nil {
+			return nil, err
+		}
+		stats.ViewsByDay = append(stats.ViewsByDay, day)
+	}
+	return &stats, rows.Err()
+}
+
+// median returns the middle value, averaging the two middle values of an even
+// count, or nil when there are none. It sorts values in place.
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	result := values[mid]
+	if len(values)%2 == 0 {
+		result = (values[mid-1] + values[mid]) / 2
+	}
+	return &result
+}
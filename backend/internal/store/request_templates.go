@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"lotbuy-backend/internal/models"
+)
+
+const templateColumns = `id, user_id, organization_id, name, lot, created_at, updated_at`
+
+type CreateRequestTemplateParams struct {
+	UserID         int64
+	OrganizationID *int64
+	Name           string
+	Lot            []byte
+}
+
+type UpdateRequestTemplateParams struct {
+	ID             int64
+	UserID         int64
+	OrganizationID *sql.NullInt64
+	Name           *string
+	Lot            *[]byte
+}
+
+func (s *Store) CreateRequestTemplate(ctx context.Context, params CreateRequestTemplateParams) (*models.RequestTemplate, error) {
+	query := `
+        INSERT INTO request_templates (user_id, organization_id, name, lot)
+        VALUES ($1, $2, $3, $4)
+        RETURNING ` + templateColumns
+
+	var tmpl models.RequestTemplate
+	if err := s.db.QueryRowxContext(ctx, query, params.UserID, params.OrganizationID, params.Name, params.Lot).StructScan(&tmpl); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+func (s *Store) GetRequestTemplate(ctx context.Context, id int64) (*models.RequestTemplate, error) {
+	var tmpl models.RequestTemplate
+	if err := s.db.QueryRowxContext(ctx, `SELECT `+templateColumns+` FROM request_templates WHERE id = $1`, id).StructScan(&tmpl); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// ListRequestTemplates returns the user's own templates and those shared with
+// organizations they belong to.
+func (s *Store) ListRequestTemplates(ctx context.Context, userID int64) ([]models.RequestTemplate, error) {
+	query := `
+        SELECT ` + templateColumns + ` FROM request_templates
+        WHERE user_id = $1
+           OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $1)
+        ORDER BY name ASC, id ASC
+    `
+
+	rows, err := s.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.RequestTemplate{}
+	for rows.Next() {
+		var tmpl models.RequestTemplate
+		if err := rows.StructScan(&tmpl); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, rows.Err()
+}
+
+func (s *Store) UpdateRequestTemplate(ctx context.Context, params UpdateRequestTemplateParams) (*models.RequestTemplate, error) {
+	setClauses := make([]string, 0, 4)
+	args := make([]interface{}, 0, 5)
+	idx := 1
+
+	if params.Name != nil {
+		setClauses = append(setClauses, fmt.Sprintf("name = $%d", idx))
+		args = append(args, *params.Name)
+		idx++
+	}
+	if params.OrganizationID != nil {
+		setClauses = append(setClauses, fmt.Sprintf("organization_id = $%d", idx))
+		args = append(args, *params.OrganizationID)
+		idx++
+	}
+	if params.Lot != nil {
+		setClauses = append(setClauses, fmt.Sprintf("lot = $%d", idx))
+		args = append(args, *params.Lot)
+		idx++
+	}
+	setClauses = append(setClauses, "updated_at = NOW()")
+	args = append(args, params.ID, params.UserID)
+
+	query := fmt.Sprintf(`UPDATE request_templates SET %s WHERE id = $%d AND user_id = $%d RETURNING %s`,
+		strings.Join(setClauses, ", "), idx, idx+1, templateColumns)
+
+	var tmpl models.RequestTemplate
+	if err := s.db.QueryRowxContext(ctx, query, args...).StructScan(&tmpl); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// DeleteRequestTemplate removes a template and stops every series that
+// published from it. The series keep their lots and can be resumed once they
+// point at another template.
+func (s *Store) DeleteRequestTemplate(ctx context.Context, id, userID int64) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE request_series
+        SET active = FALSE, next_run_at = NULL, last_error = 'template deleted', updated_at = NOW()
+        WHERE template_id = (SELECT id FROM request_templates WHERE id = $1 AND user_id = $2)
+    `, id, userID); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM request_templates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// DuplicateRequest creates a new lot from params and copies the image gallery
+// of the source lot to it.
+func (s *Store) DuplicateRequest(ctx context.Context, sourceID int64, params CreateRequestParams) (*models.Request, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	req, err := createRequest(ctx, tx, params)
+	if err != nil {
+		return nil, err
+	}
+	// The source gallery replaces the cover created from the image URL.
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM request_images
+        WHERE request_id = $2 AND EXISTS (SELECT 1 FROM request_images WHERE request_id = $1)
+    `, sourceID, req.ID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO request_images (request_id, url, alt_text, width, height, position, is_cover)
+        SELECT $2, url, alt_text, width, height, position, is_cover
+        FROM request_images WHERE request_id = $1
+    `, sourceID, req.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowxContext(ctx, `SELECT `+requestColumns+` FROM requests WHERE id = $1`, req.ID).StructScan(req); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return req, nil
+}
@@ -9,9 +9,43 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+
+	"lotbuy-backend/internal/geo"
 	"lotbuy-backend/internal/models"
 )
 
+// requestColumns lists the columns scanned into models.Request. Drafts may be
+// saved without a budget or currency, so both are coalesced to zero values.
+// The cover image falls back to the legacy image_url column.
+const requestColumns = `id, title, description, COALESCE(budget_amount, 0) AS budget_amount,
+        COALESCE(currency_code, '') AS currency_code, buyer_user_id, buyer_name,
+        buyer_avatar_url, buyer_rating, image_url, category, subcategory,
+        location_city, location_region, location_country, latitude, longitude, deadline_at,
+        condition, quantity, unit, status, visibility, publish_at, attributes, created_at, updated_at,
+        COALESCE((SELECT ri.url FROM request_images ri WHERE ri.request_id = requests.id
+                  ORDER BY ri.is_cover DESC, ri.position LIMIT 1), image_url) AS cover_image_url,
+        (SELECT COUNT(*) FROM request_watchers rw WHERE rw.request_id = requests.id) AS watcher_count, revision, series_id, deleted_at,
+        mode, auction_ends_at, auction_duration_hours, auction_min_decrement, auction_extension_minutes,
+        auction_award, auction_closed_at, auction_winner_offer_id, sealed_until, sealed_duration_hours, offer_visibility`
+
+// auctionStartSQL and sealedStartSQL are the end of an auction or of sealed
+// bidding that starts now: its fixed end, or its duration from now.
+const (
+	auctionStartSQL = `COALESCE(auction_ends_at, NOW() + auction_duration_hours * INTERVAL '1 hour')`
+	sealedStartSQL  = `COALESCE(sealed_until, NOW() + sealed_duration_hours * INTERVAL '1 hour')`
+)
+
+var ErrRequestNotDraft = errors.New("request is already published")
+
+// ErrRequestHasDeal is returned when deleting a lot that a deal was made on
+// and not cancelled; the deal and its history belong to the seller as well.
+var ErrRequestHasDeal = errors.New("request has a deal that is not cancelled and cannot be deleted")
+
+// RequestRestoreWindow is how long a deleted lot can be restored before it is
+// purged for good.
+const RequestRestoreWindow = 30 * 24 * time.Hour
+
 type CreateRequestParams struct {
 	Title           string
 	Description     *string
@@ -27,14 +61,53 @@ type CreateRequestParams struct {
 	LocationCity    *string
 	LocationRegion  *string
 	LocationCountry *string
+	Latitude        *float64
+	Longitude       *float64
 	DeadlineAt      *time.Time
+	Condition       *string
+	Quantity        int
+	Unit            string
+	Status          string
+	Visibility      string
+	OfferVisibility string
+	PublishAt       *time.Time
+	Attributes      []byte
+	SeriesID        *int64
+	ModeSettings    LotModeSettings
+}
+
+// LotModeSettings configure how sellers compete on a lot. Mode is empty for
+// standard lots. A lot published later starts its auction or sealed bidding
+// then, lasting the given number of hours unless its end is fixed.
+type LotModeSettings struct {
+	Mode                    string
+	AuctionEndsAt           *time.Time
+	AuctionDurationHours    *int
+	AuctionMinDecrement     *float64
+	AuctionExtensionMinutes *int
+	AuctionAward            *string
+	SealedUntil             *time.Time
+	SealedDurationHours     *int
 }
 
 type ListRequestsParams struct {
-	Status   *string
-	BuyerID  *int64
-	Limit    *int
-	OnlyOpen bool
+	Status      *string
+	BuyerID     *int64
+	Category    *string
+	Subcategory *string
+	Condition   *string
+	MinQuantity *int
+	Near        *geo.Point
+	RadiusKm    float64
+	Limit       *int
+	OnlyOpen    bool
+	// ViewerID and ViewerEmail identify the caller so private lots they own or
+	// were invited to are listed; anonymous callers only see public lots.
+	ViewerID    *int64
+	ViewerEmail string
+	// IncludeUnpublished returns drafts and scheduled lots as well. It must
+	// only be set when BuyerID restricts the listing to the viewer's own lots.
+	IncludeUnpublished bool
 }
 
 type UpdateRequestParams struct {
@@ -50,28 +123,134 @@ type UpdateRequestParams struct {
 	LocationCity    *sql.NullString
 	LocationRegion  *sql.NullString
 	LocationCountry *sql.NullString
+	Latitude        *sql.NullFloat64
+	Longitude       *sql.NullFloat64
 	DeadlineAt      *sql.NullTime
+	Condition       *sql.NullString
+	Quantity        *int
+	Unit            *string
+	Visibility      *string
+	OfferVisibility *string
+	Attributes      *[]byte
+	// IfUpdatedAt makes the update conditional on the lot still being at the
+	// version the caller read; otherwise ErrRequestModified is returned.
+	IfUpdatedAt *time.Time
 }
 
+// ErrRequestModified is returned by a conditional update when the lot changed
+// since the caller read it.
+var ErrRequestModified = errors.New("request was modified concurrently")
+
 func (s *Store) CreateRequest(ctx context.Context, params CreateRequestParams) (*models.Request, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	req, err := createRequest(ctx, tx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return req, nil
+}
+
+// CreateRequests inserts several lots in one transaction; either all of them
+// are created or none.
+func (s *Store) CreateRequests(ctx context.Context, params []CreateRequestParams) ([]models.Request, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	requests := make([]models.Request, 0, len(params))
+	for _, p := range params {
+		req, err := createRequest(ctx, tx, p)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *req)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return requests, nil
+}
+
+// createRequest inserts the lot. A lot created with an image URL gets it as
+// the cover of its gallery.
+func createRequest(ctx context.Context, q sqlx.ExtContext, params CreateRequestParams) (*models.Request, error) {
 	query := `
         INSERT INTO requests (
             title, description, budget_amount, currency_code, buyer_user_id, buyer_name,
             buyer_avatar_url, buyer_rating, image_url, category, subcategory,
-            location_city, location_region, location_country, deadline_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-        RETURNING id, title, description, budget_amount, currency_code, buyer_user_id, buyer_name,
-                  buyer_avatar_url, buyer_rating, image_url, category, subcategory,
-                  location_city, location_region, location_country, deadline_at,
-                  status, created_at, updated_at
-    `
+            location_city, location_region, location_country, latitude, longitude, deadline_at,
+            condition, quantity, unit, status, visibility, publish_at, attributes, series_id,
+            mode, auction_ends_at, auction_duration_hours, auction_min_decrement,
+            auction_extension_minutes, auction_award, sealed_until, sealed_duration_hours, offer_visibility
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25,
+            $26, $27, $28, $29, $30, $31, $32, $33, $34)
+        RETURNING ` + requestColumns
+
+	status := params.Status
+	if status == "" {
+		status = "open"
+	}
+
+	quantity := params.Quantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+	unit := params.Unit
+	if unit == "" {
+		unit = "pcs"
+	}
+	visibility := params.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
+	offerVisibility := params.OfferVisibility
+	if offerVisibility == "" {
+		offerVisibility = "summary"
+	}
+	mode := params.ModeSettings.Mode
+	if mode == "" {
+		mode = "standard"
+	}
+
+	var budget *float64
+	if params.BudgetAmount > 0 {
+		budget = &params.BudgetAmount
+	}
+	var currency *string
+	if code := strings.ToUpper(strings.TrimSpace(params.CurrencyCode)); code != "" {
+		currency = &code
+	}
 
 	var req models.Request
-	if err := s.db.QueryRowxContext(ctx, query,
+	if err := q.QueryRowxContext(ctx, query,
 		params.Title,
 		params.Description,
-		params.BudgetAmount,
-		strings.ToUpper(params.CurrencyCode),
+		budget,
+		currency,
 		params.BuyerID,
 		params.BuyerName,
 		params.BuyerAvatar,
@@ -82,23 +261,60 @@ func (s *Store) CreateRequest(ctx context.Context, params CreateRequestParams) (
 		params.LocationCity,
 		params.LocationRegion,
 		params.LocationCountry,
+		params.Latitude,
+		params.Longitude,
 		params.DeadlineAt,
+		params.Condition,
+		quantity,
+		unit,
+		status,
+		visibility,
+		params.PublishAt,
+		jsonValue(params.Attributes),
+		params.SeriesID,
+		mode,
+		params.ModeSettings.AuctionEndsAt,
+		params.ModeSettings.AuctionDurationHours,
+		params.ModeSettings.AuctionMinDecrement,
+		params.ModeSettings.AuctionExtensionMinutes,
+		params.ModeSettings.AuctionAward,
+		params.ModeSettings.SealedUntil,
+		params.ModeSettings.SealedDurationHours,
+		offerVisibility,
 	).StructScan(&req); err != nil {
 		return nil, err
 	}
 
+	if req.ImageURL != nil {
+		if _, err := q.ExecContext(ctx, `
+            INSERT INTO request_images (request_id, url, position, is_cover) VALUES ($1, $2, 0, TRUE)
+        `, req.ID, *req.ImageURL); err != nil {
+			return nil, err
+		}
+	}
+
 	return &req, nil
 }
 
 func (s *Store) ListRequests(ctx context.Context, params ListRequestsParams) ([]models.Request, error) {
-	base := `SELECT id, title, description, budget_amount, currency_code, buyer_user_id, buyer_name,
-                    buyer_avatar_url, buyer_rating, image_url, category, subcategory,
-                    location_city, location_region, location_country, deadline_at,
-                    status, created_at, updated_at
-             FROM requests`
+	columns := requestColumns
 	var clauses []string
 	var args []interface{}
 	idx := 1
+	if params.Near != nil {
+		minLat, maxLat, minLng, maxLng := geo.BoundingBox(*params.Near, params.RadiusKm)
+		distance := distanceKmSQL("latitude", "longitude", fmt.Sprintf("$%d", idx), fmt.Sprintf("$%d", idx+1))
+		columns += ", " + distance + " AS distance_km"
+		clauses = append(clauses,
+			fmt.Sprintf("latitude BETWEEN $%d AND $%d", idx+2, idx+3),
+			fmt.Sprintf("longitude BETWEEN $%d AND $%d", idx+4, idx+5),
+			fmt.Sprintf("%s <= $%d", distance, idx+6),
+		)
+		args = append(args, params.Near.Latitude, params.Near.Longitude, minLat, maxLat, minLng, maxLng, params.RadiusKm)
+		idx += 7
+	}
+	base := `SELECT ` + columns + ` FROM requests`
+	clauses = append(clauses, "deleted_at IS NULL")
 	if params.Status != nil {
 		clauses = append(clauses, "status = $"+strconv.Itoa(idx))
 		args = append(args, *params.Status)
@@ -112,10 +328,43 @@ func (s *Store) ListRequests(ctx context.Context, params ListRequestsParams) ([]
 		args = append(args, *params.BuyerID)
 		idx++
 	}
-	if len(clauses) > 0 {
-		base += " WHERE " + strings.Join(clauses, " AND ")
+	if params.Category != nil {
+		clauses = append(clauses, "category = $"+strconv.Itoa(idx))
+		args = append(args, *params.Category)
+		idx++
+	}
+	if params.Subcategory != nil {
+		clauses = append(clauses, "subcategory = $"+strconv.Itoa(idx))
+		args = append(args, *params.Subcategory)
+		idx++
+	}
+	if params.Condition != nil {
+		clauses = append(clauses, "condition = $"+strconv.Itoa(idx))
+		args = append(args, *params.Condition)
+		idx++
+	}
+	if params.MinQuantity != nil {
+		clauses = append(clauses, "quantity >= $"+strconv.Itoa(idx))
+		args = append(args, *params.MinQuantity)
+		idx++
+	}
+	if !params.IncludeUnpublished || params.BuyerID == nil {
+		clauses = append(clauses, "status NOT IN ('draft', 'scheduled')")
+	}
+	if params.ViewerID != nil {
+		clauses = append(clauses, fmt.Sprintf("(visibility = 'public' OR buyer_user_id = $%d OR %s)",
+			idx, invitedSQL("requests.id", fmt.Sprintf("$%d", idx), fmt.Sprintf("$%d", idx+1))))
+		args = append(args, *params.ViewerID, params.ViewerEmail)
+		idx += 2
+	} else {
+		clauses = append(clauses, "visibility = 'public'")
+	}
+	base += " WHERE " + strings.Join(clauses, " AND ")
+	if params.Near != nil {
+		base += " ORDER BY distance_km ASC"
+	} else {
+		base += " ORDER BY COALESCE(publish_at, created_at) DESC"
 	}
-	base += " ORDER BY created_at DESC"
 	if params.Limit != nil && *params.Limit > 0 {
 		base += " LIMIT $" + strconv.Itoa(idx)
 		args = append(args, *params.Limit)
@@ -140,11 +389,7 @@ func (s *Store) ListRequests(ctx context.Context, params ListRequestsParams) ([]
 }
 
 func (s *Store) GetRequest(ctx context.Context, id int64) (*models.Request, error) {
-	query := `SELECT id, title, description, budget_amount, currency_code, buyer_user_id, buyer_name,
-                     buyer_avatar_url, buyer_rating, image_url, category, subcategory,
-                     location_city, location_region, location_country, deadline_at,
-                     status, created_at, updated_at
-              FROM requests WHERE id = $1`
+	query := `SELECT ` + requestColumns + ` FROM requests WHERE id = $1 AND deleted_at IS NULL`
 
 	var req models.Request
 	if err := s.db.QueryRowxContext(ctx, query, id).StructScan(&req); err != nil {
@@ -158,7 +403,9 @@ func (s *Store) UpdateRequestStatus(ctx context.Context, id int64, status string
 	return err
 }
 
-func (s *Store) UpdateRequest(ctx context.Context, params UpdateRequestParams) (*models.Request, error) {
+// UpdateRequest applies the changes and records them as a new revision of the
+// lot. The revision is nil when the update left every field unchanged.
+func (s *Store) UpdateRequest(ctx context.Context, params UpdateRequestParams) (*models.Request, *models.RequestRevision, error) {
 	setClauses := make([]string, 0, 12)
 	args := make([]interface{}, 0, 14)
 	idx := 1
@@ -213,42 +460,306 @@ func (s *Store) UpdateRequest(ctx context.Context, params UpdateRequestParams) (
 		args = append(args, params.LocationCountry)
 		idx++
 	}
+	if params.Latitude != nil && params.Longitude != nil {
+		setClauses = append(setClauses, fmt.Sprintf("latitude = $%d, longitude = $%d", idx, idx+1))
+		args = append(args, params.Latitude, params.Longitude)
+		idx += 2
+	}
 	if params.DeadlineAt != nil {
 		setClauses = append(setClauses, fmt.Sprintf("deadline_at = $%d", idx))
 		args = append(args, params.DeadlineAt)
 		idx++
 	}
+	if params.Condition != nil {
+		setClauses = append(setClauses, fmt.Sprintf("condition = $%d", idx))
+		args = append(args, params.Condition)
+		idx++
+	}
+	if params.Quantity != nil {
+		setClauses = append(setClauses, fmt.Sprintf("quantity = $%d", idx))
+		args = append(args, *params.Quantity)
+		idx++
+	}
+	if params.Unit != nil {
+		setClauses = append(setClauses, fmt.Sprintf("unit = $%d", idx))
+		args = append(args, *params.Unit)
+		idx++
+	}
+	if params.Visibility != nil {
+		setClauses = append(setClauses, fmt.Sprintf("visibility = $%d", idx))
+		args = append(args, *params.Visibility)
+		idx++
+	}
+	if params.OfferVisibility != nil {
+		setClauses = append(setClauses, fmt.Sprintf("offer_visibility = $%d", idx))
+		args = append(args, *params.OfferVisibility)
+		idx++
+	}
+	if params.Attributes != nil {
+		setClauses = append(setClauses, fmt.Sprintf("attributes = $%d", idx))
+		args = append(args, jsonValue(*params.Attributes))
+		idx++
+	}
 
 	if len(setClauses) == 0 {
-		return nil, errors.New("no fields to update")
+		return nil, nil, errors.New("no fields to update")
 	}
 
 	setClauses = append(setClauses, "updated_at = NOW()")
 
 	args = append(args, params.ID, params.BuyerID)
 
-	query := fmt.Sprintf(`UPDATE requests SET %s WHERE id = $%d AND buyer_user_id = $%d
-                RETURNING id, title, description, budget_amount, currency_code, buyer_user_id, buyer_name,
-                          buyer_avatar_url, buyer_rating, image_url, category, subcategory,
-                          location_city, location_region, location_country, deadline_at,
-                          status, created_at, updated_at`,
-		strings.Join(setClauses, ", "), idx, idx+1)
+	query := fmt.Sprintf(`UPDATE requests SET %s WHERE id = $%d AND buyer_user_id = $%d RETURNING %s`,
+		strings.Join(setClauses, ", "), idx, idx+1, requestColumns)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var before models.Request
+	if err := tx.QueryRowxContext(ctx,
+		`SELECT `+requestColumns+` FROM requests WHERE id = $1 AND buyer_user_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		params.ID, params.BuyerID,
+	).StructScan(&before); err != nil {
+		return nil, nil, err
+	}
+	if params.IfUpdatedAt != nil && !before.UpdatedAt.Equal(*params.IfUpdatedAt) {
+		return nil, nil, ErrRequestModified
+	}
+	if before.BiddingOpen(time.Now()) && changesBidTerms(&before, params) {
+		return nil, nil, ErrBidTermsLocked
+	}
+	// The gallery cover wins over image_url, so a new imageUrl has to go
+	// through the gallery to show up.
+	if params.ImageURL != nil {
+		if err := setCoverImageURL(ctx, tx, params.ID, *params.ImageURL); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	var req models.Request
-	if err := s.db.QueryRowxContext(ctx, query, args...).StructScan(&req); err != nil {
-		return nil, err
+	if err := tx.QueryRowxContext(ctx, query, args...).StructScan(&req); err != nil {
+		return nil, nil, err
 	}
 
-	return &req, nil
+	revision, err := recordRequestRevision(ctx, tx, &before, &req, params.BuyerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	committed = true
+
+	return &req, revision, nil
+}
+
+// changesBidTerms reports whether params change the currency, quantity or
+// budget bids on the lot are made against.
+func changesBidTerms(req *models.Request, params UpdateRequestParams) bool {
+	if params.CurrencyCode != nil && !strings.EqualFold(*params.CurrencyCode, req.CurrencyCode) {
+		return true
+	}
+	if params.Quantity != nil && *params.Quantity != req.Quantity {
+		return true
+	}
+	return params.BudgetAmount != nil && *params.BudgetAmount != req.BudgetAmount
 }
 
+// DeleteRequest marks the lot deleted. Offers, messages and images stay until
+// the lot is purged, so the owner can restore it within RequestRestoreWindow.
 func (s *Store) DeleteRequest(ctx context.Context, id, buyerID int64) error {
-	res, err := s.db.ExecContext(ctx, `DELETE FROM requests WHERE id = $1 AND buyer_user_id = $2`, id, buyerID)
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
-		return sql.ErrNoRows
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var hasDeal bool
+	if err := tx.QueryRowxContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM deals WHERE request_id = r.id AND status <> 'cancelled')
+        FROM requests r
+        WHERE r.id = $1 AND r.buyer_user_id = $2 AND r.deleted_at IS NULL
+        FOR UPDATE
+    `, id, buyerID).Scan(&hasDeal); err != nil {
+		return err
+	}
+	if hasDeal {
+		return ErrRequestHasDeal
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE requests SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// ListDeletedRequests returns the user's deleted lots that can still be
+// restored, most recently deleted first.
+func (s *Store) ListDeletedRequests(ctx context.Context, buyerID int64, now time.Time) ([]models.Request, error) {
+	query := `SELECT ` + requestColumns + ` FROM requests
+              WHERE buyer_user_id = $1 AND deleted_at > $2
+              ORDER BY deleted_at DESC`
+
+	rows, err := s.db.QueryxContext(ctx, query, buyerID, now.Add(-RequestRestoreWindow))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.Request{}
+	for rows.Next() {
+		var r models.Request
+		if err := rows.StructScan(&r); err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, rows.Err()
+}
+
+// RestoreRequest undeletes a lot owned by buyerID while it is still within the
+// restore window.
+func (s *Store) RestoreRequest(ctx context.Context, id, buyerID int64, now time.Time) (*models.Request, error) {
+	query := `UPDATE requests
+              SET deleted_at = NULL, updated_at = NOW()
+              WHERE id = $1 AND buyer_user_id = $2 AND deleted_at > $3
+              RETURNING ` + requestColumns
+
+	var req models.Request
+	if err := s.db.QueryRowxContext(ctx, query, id, buyerID, now.Add(-RequestRestoreWindow)).StructScan(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// PurgeDeletedRequests permanently removes lots deleted longer ago than the
+// restore window, together with everything that cascades from them.
+func (s *Store) PurgeDeletedRequests(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+        DELETE FROM requests r
+        WHERE r.deleted_at < $1
+          AND NOT EXISTS (SELECT 1 FROM deals d WHERE d.request_id = r.id AND d.status <> 'cancelled')
+    `, now.Add(-RequestRestoreWindow))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PublishRequest moves a draft or scheduled lot owned by buyerID to open, or to
+// scheduled when publishAt lies in the future. Validation of the lot contents
+// is the caller's responsibility.
+func (s *Store) PublishRequest(ctx context.Context, id, buyerID int64, publishAt *time.Time) (*models.Request, error) {
+	now := time.Now()
+	status := "open"
+	if publishAt != nil && publishAt.After(now) {
+		status = "scheduled"
+	} else {
+		publishAt = &now
+	}
+
+	query := `UPDATE requests
+              SET status = $1, publish_at = $2, updated_at = NOW(),
+                  auction_ends_at = CASE WHEN $1 = 'open' THEN ` + auctionStartSQL + ` ELSE auction_ends_at END,
+                  sealed_until = CASE WHEN $1 = 'open' THEN ` + sealedStartSQL + ` ELSE sealed_until END
+              WHERE id = $3 AND buyer_user_id = $4 AND status IN ('draft', 'scheduled') AND deleted_at IS NULL
+              RETURNING ` + requestColumns
+
+	var req models.Request
+	if err := s.db.QueryRowxContext(ctx, query, status, publishAt, id, buyerID).StructScan(&req); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRequestNotDraft
+		}
+		return nil, err
 	}
+	return &req, nil
+}
+
+// RequestLocation is the free-text location of a lot awaiting geocoding.
+type RequestLocation struct {
+	ID      int64   `db:"id"`
+	City    string  `db:"location_city"`
+	Country *string `db:"location_country"`
+}
+
+// ListRequestsToGeocode returns up to limit lots that have a city but no
+// coordinates and have not been geocoded yet.
+func (s *Store) ListRequestsToGeocode(ctx context.Context, limit int) ([]RequestLocation, error) {
+	var locations []RequestLocation
+	err := s.db.SelectContext(ctx, &locations, `
+        SELECT id, location_city, location_country FROM requests
+        WHERE latitude IS NULL AND location_city IS NOT NULL AND geocoded_at IS NULL
+        ORDER BY id LIMIT $1
+    `, limit)
+	return locations, err
+}
+
+// SetRequestGeocoded marks a lot as geocoded, storing point when its city
+// was found. Coordinates set in the meantime are kept.
+func (s *Store) SetRequestGeocoded(ctx context.Context, id int64, point *geo.Point) error {
+	var latitude, longitude *float64
+	if point != nil {
+		latitude, longitude = &point.Latitude, &point.Longitude
+	}
+	_, err := s.db.ExecContext(ctx, `
+        UPDATE requests
+        SET geocoded_at = NOW(),
+            latitude = COALESCE(latitude, $2), longitude = COALESCE(longitude, $3),
+            updated_at = CASE WHEN latitude IS NULL AND $2::float8 IS NOT NULL THEN NOW() ELSE updated_at END
+        WHERE id = $1
+    `, id, latitude, longitude)
 	return err
 }
+
+// PublishDueRequests opens every scheduled lot whose publish time has passed
+// and returns the lots that were published.
+func (s *Store) PublishDueRequests(ctx context.Context, now time.Time) ([]models.Request, error) {
+	query := `UPDATE requests
+              SET status = 'open', updated_at = NOW(), auction_ends_at = ` + auctionStartSQL + `,
+                  sealed_until = ` + sealedStartSQL + `
+              WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+              RETURNING ` + requestColumns
+
+	rows, err := s.db.QueryxContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.Request{}
+	for rows.Next() {
+		var r models.Request
+		if err := rows.StructScan(&r); err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, rows.Err()
+}
+
+// distanceKmSQL builds a haversine expression for the distance in kilometres
+// between two coordinate pairs given as SQL expressions.
+func distanceKmSQL(lat1, lng1, lat2, lng2 string) string {
+	return fmt.Sprintf(`(2 * 6371 * asin(least(1, sqrt(
+            power(sin(radians(%[1]s - %[3]s) / 2), 2) +
+            cos(radians(%[3]s)) * cos(radians(%[1]s)) * power(sin(radians(%[2]s - %[4]s) / 2), 2)))))`,
+		lat1, lng1, lat2, lng2)
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lotbuy-backend/internal/models"
+)
+
+const savedSearchColumns = `id, user_id, name, category, subcategory, condition, min_quantity,
+        near_latitude, near_longitude, radius_km, delivery, last_digest_at, created_at, updated_at`
+
+type CreateSavedSearchParams struct {
+	UserID        int64
+	Name          string
+	Category      *string
+	Subcategory   *string
+	Condition     *string
+	MinQuantity   *int
+	NearLatitude  *float64
+	NearLongitude *float64
+	RadiusKm      *float64
+	Delivery      string
+}
+
+type UpdateSavedSearchParams struct {
+	ID       int64
+	UserID   int64
+	Name     *string
+	Delivery *string
+}
+
+func (s *Store) CreateSavedSearch(ctx context.Context, params CreateSavedSearchParams) (*models.SavedSearch, error) {
+	query := `
+        INSERT INTO saved_searches (
+            user_id, name, category, subcategory, condition, min_quantity,
+            near_latitude, near_longitude, radius_km, delivery
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING ` + savedSearchColumns
+
+	var search models.SavedSearch
+	if err := s.db.QueryRowxContext(ctx, query,
+		params.UserID,
+		params.Name,
+		params.Category,
+		params.Subcategory,
+		params.Condition,
+		params.MinQuantity,
+		params.NearLatitude,
+		params.NearLongitude,
+		params.RadiusKm,
+		params.Delivery,
+	).StructScan(&search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (s *Store) ListSavedSearches(ctx context.Context, userID int64) ([]models.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + ` FROM saved_searches WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		var search models.SavedSearch
+		if err := rows.StructScan(&search); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+func (s *Store) UpdateSavedSearch(ctx context.Context, params UpdateSavedSearchParams) (*models.SavedSearch, error) {
+	setClauses := make([]string, 0, 3)
+	args := make([]interface{}, 0, 4)
+	idx := 1
+
+	if params.Name != nil {
+		setClauses = append(setClauses, fmt.Sprintf("name = $%d", idx))
+		args = append(args, *params.Name)
+		idx++
+	}
+	if params.Delivery != nil {
+		setClauses = append(setClauses, fmt.Sprintf("delivery = $%d", idx))
+		args = append(args, *params.Delivery)
+		idx++
+	}
+	setClauses = append(setClauses, "updated_at = NOW()")
+	args = append(args, params.ID, params.UserID)
+
+	query := fmt.Sprintf(`UPDATE saved_searches SET %s WHERE id = $%d AND user_id = $%d RETURNING %s`,
+		strings.Join(setClauses, ", "), idx, idx+1, savedSearchColumns)
+
+	var search models.SavedSearch
+	if err := s.db.QueryRowxContext(ctx, query, args...).StructScan(&search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (s *Store) DeleteSavedSearch(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// ProcessSearchAlerts matches up to limit newly published lots against every
+// saved search, records the matches, and notifies users with instant delivery
+// right away. Matching happens off the request insert path; the lots handled
+// are marked so each is matched exactly once. It returns the number of lots
+// processed.
+func (s *Store) ProcessSearchAlerts(ctx context.Context, limit int) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var requestIDs []int64
+	if err := tx.SelectContext(ctx, &requestIDs, `
+        SELECT id FROM requests
+        WHERE search_alerts_processed_at IS NULL AND status = 'open' AND deleted_at IS NULL
+        ORDER BY id
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    `, limit); err != nil {
+		return 0, err
+	}
+	if len(requestIDs) == 0 {
+		return 0, nil
+	}
+
+	distance := distanceKmSQL("r.latitude", "r.longitude", "ss.near_latitude", "ss.near_longitude")
+	matchQuery := `
+        INSERT INTO saved_search_matches (saved_search_id, request_id)
+        SELECT ss.id, r.id
+        FROM saved_searches ss
+        INNER JOIN requests r ON r.id = $1
+        WHERE ss.user_id <> COALESCE(r.buyer_user_id, 0)
+          AND r.visibility = 'public'
+          AND (ss.category IS NULL OR ss.category = r.category)
+          AND (ss.subcategory IS NULL OR ss.subcategory = r.subcategory)
+          AND (ss.condition IS NULL OR ss.condition = r.condition)
+          AND (ss.min_quantity IS NULL OR r.quantity >= ss.min_quantity)
+          AND (ss.near_latitude IS NULL OR (r.latitude IS NOT NULL AND ` + distance + ` <= ss.radius_km))
+        ON CONFLICT DO NOTHING`
+
+	for _, requestID := range requestIDs {
+		if _, err := tx.ExecContext(ctx, matchQuery, requestID); err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO notifications (user_id, type, title, body, metadata)
+            SELECT ss.user_id, 'search.match',
+                   'Новый лот по сохранённому поиску «' || ss.name || '»',
+                   r.title,
+                   jsonb_build_object('savedSearchId', ss.id, 'requestId', r.id)
+            FROM saved_search_matches m
+            INNER JOIN saved_searches ss ON ss.id = m.saved_search_id
+            INNER JOIN requests r ON r.id = m.request_id
+            WHERE m.request_id = $1 AND m.notified_at IS NULL AND ss.delivery = 'instant'
+        `, requestID); err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE saved_search_matches m SET notified_at = NOW()
+            FROM saved_searches ss
+            WHERE ss.id = m.saved_search_id AND m.request_id = $1
+              AND m.notified_at IS NULL AND ss.delivery = 'instant'
+        `, requestID); err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE requests SET search_alerts_processed_at = NOW() WHERE id = $1`, requestID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	committed = true
+	return len(requestIDs), nil
+}
+
+// SendSearchDigests sends one notification per daily saved search that has
+// pending matches and has not received a digest in the last 24 hours.
+func (s *Store) SendSearchDigests(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var searchIDs []int64
+	if err := tx.SelectContext(ctx, &searchIDs, `
+        SELECT ss.id FROM saved_searches ss
+        WHERE ss.delivery = 'daily'
+          AND (ss.last_digest_at IS NULL OR ss.last_digest_at <= $1::timestamptz - INTERVAL '24 hours')
+          AND EXISTS (SELECT 1 FROM saved_search_matches m WHERE m.saved_search_id = ss.id AND m.notified_at IS NULL)
+        FOR UPDATE SKIP LOCKED
+    `, now); err != nil {
+		return 0, err
+	}
+
+	for _, searchID := range searchIDs {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO notifications (user_id, type, title, body, metadata)
+            SELECT ss.user_id, 'search.digest',
+                   'Новые лоты по сохранённому поиску «' || ss.name || '»',
+                   'Новых лотов: ' || COUNT(m.request_id),
+                   jsonb_build_object('savedSearchId', ss.id, 'requestIds', jsonb_agg(m.request_id ORDER BY m.request_id))
+            FROM saved_searches ss
+            INNER JOIN saved_search_matches m ON m.saved_search_id = ss.id AND m.notified_at IS NULL
+            WHERE ss.id = $1
+            GROUP BY ss.id, ss.user_id, ss.name
+        `, searchID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE saved_search_matches SET notified_at = $2 WHERE saved_search_id = $1 AND notified_at IS NULL`, searchID, now); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE saved_searches SET last_digest_at = $2 WHERE id = $1`, searchID, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	committed = true
+	return len(searchIDs), nil
+}
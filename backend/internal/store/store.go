@@ -13,3 +13,12 @@ func New(db *sqlx.DB) *Store {
 func (s *Store) DB() *sqlx.DB {
 	return s.db
 }
+
+// jsonValue maps an empty JSON document to SQL NULL so optional JSONB columns
+// are not written as empty strings.
+func jsonValue(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
+}
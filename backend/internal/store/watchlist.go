@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"lotbuy-backend/internal/models"
+)
+
+func (s *Store) WatchRequest(ctx context.Context, userID, requestID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+        INSERT INTO request_watchers (user_id, request_id)
+        VALUES ($1, $2)
+        ON CONFLICT DO NOTHING
+    `, userID, requestID)
+	return err
+}
+
+func (s *Store) UnwatchRequest(ctx context.Context, userID, requestID int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM request_watchers WHERE user_id = $1 AND request_id = $2`, userID, requestID)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// ListWatchedRequests returns the lots a user watches, most recently watched
+// first.
+func (s *Store) ListWatchedRequests(ctx context.Context, userID int64) ([]models.Request, error) {
+	query := `SELECT ` + requestColumns + ` FROM requests
+              WHERE id IN (SELECT request_id FROM request_watchers WHERE user_id = $1) AND deleted_at IS NULL
+              ORDER BY (SELECT w.created_at FROM request_watchers w
+                        WHERE w.request_id = requests.id AND w.user_id = $1) DESC`
+
+	rows, err := s.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.Request{}
+	for rows.Next() {
+		var req models.Request
+		if err := rows.StructScan(&req); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// NotifyRequestWatchers sends the same notification to every watcher of a lot.
+func (s *Store) NotifyRequestWatchers(ctx context.Context, requestID int64, notificationType, title string, body *string, metadata []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+        INSERT INTO notifications (user_id, type, title, body, metadata)
+        SELECT user_id, $2, $3, $4, $5::jsonb FROM request_watchers WHERE request_id = $1
+    `, requestID, notificationType, title, body, metadata)
+	return err
+}
@@ -0,0 +1,11 @@
+ALTER TABLE requests
+    ALTER COLUMN budget_amount DROP NOT NULL,
+    ALTER COLUMN currency_code DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_published_complete_chk;
+ALTER TABLE requests ADD CONSTRAINT requests_published_complete_chk
+    CHECK (status = 'draft' OR (budget_amount IS NOT NULL AND currency_code IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS requests_scheduled_publish_idx
+    ON requests(publish_at) WHERE status = 'scheduled';
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS request_images (
+    id SERIAL PRIMARY KEY,
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    alt_text TEXT,
+    width INTEGER CHECK (width IS NULL OR width > 0),
+    height INTEGER CHECK (height IS NULL OR height > 0),
+    position INTEGER NOT NULL,
+    is_cover BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS request_images_request_id_position_idx
+    ON request_images(request_id, position);
+
+CREATE UNIQUE INDEX IF NOT EXISTS request_images_single_cover_idx
+    ON request_images(request_id) WHERE is_cover;
+
+INSERT INTO request_images (request_id, url, position, is_cover)
+SELECT r.id, r.image_url, 0, TRUE
+FROM requests r
+WHERE r.image_url IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM request_images ri WHERE ri.request_id = r.id);
@@ -0,0 +1,103 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    slug TEXT NOT NULL UNIQUE CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$'),
+    names JSONB NOT NULL,
+    attribute_schema JSONB,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_position_idx ON categories(parent_id, position);
+
+INSERT INTO categories (slug, names, attribute_schema, position)
+VALUES
+  ('electronics', '{"en": "Electronics & Gadgets", "ru": "Электроника и Гаджеты"}',
+   '{"properties": {"brand": {"type": "string", "maxLength": 80, "title": {"en": "Brand", "ru": "Бренд"}},
+                    "warranty_required": {"type": "boolean", "title": {"en": "Warranty required", "ru": "Нужна гарантия"}}}}', 1),
+  ('furniture', '{"en": "Furniture & Home", "ru": "Фурнитура и Дом"}',
+   '{"properties": {"material": {"type": "string", "maxLength": 80, "title": {"en": "Material", "ru": "Материал"}},
+                    "assembly_required": {"type": "boolean", "title": {"en": "Assembly service", "ru": "Нужна сборка"}}}}', 2),
+  ('vehicles', '{"en": "Vehicles & Parts", "ru": "Автомобили и Запчасти"}',
+   '{"properties": {"make": {"type": "string", "maxLength": 80, "title": {"en": "Make", "ru": "Марка"}},
+                    "model": {"type": "string", "maxLength": 80, "title": {"en": "Model", "ru": "Модель"}},
+                    "year_from": {"type": "integer", "minimum": 1900, "maximum": 2100, "title": {"en": "Year from", "ru": "Год от"}},
+                    "mileage_max_km": {"type": "integer", "minimum": 0, "title": {"en": "Max mileage, km", "ru": "Макс. пробег, км"}}}}', 3),
+  ('fashion', '{"en": "Fashion & Accessories", "ru": "Мода и Аксессуары"}',
+   '{"properties": {"size": {"type": "string", "maxLength": 20, "title": {"en": "Size", "ru": "Размер"}},
+                    "gender": {"type": "string", "enum": ["women", "men", "unisex", "kids"], "title": {"en": "Gender", "ru": "Пол"}}}}', 4),
+  ('books', '{"en": "Books & Media", "ru": "Книги и Медиа"}',
+   '{"properties": {"author": {"type": "string", "maxLength": 120, "title": {"en": "Author", "ru": "Автор"}},
+                    "isbn": {"type": "string", "maxLength": 17, "title": {"en": "ISBN", "ru": "ISBN"}}}}', 5),
+  ('sports', '{"en": "Sports", "ru": "Спорт"}', NULL, 6),
+  ('tools', '{"en": "Tools & Equipment", "ru": "Инструменты и Оборудование"}',
+   '{"properties": {"power_source": {"type": "string", "enum": ["corded", "battery", "manual", "gas"], "title": {"en": "Power source", "ru": "Питание"}}}}', 7),
+  ('collectibles', '{"en": "Collectibles & Art", "ru": "Коллекции и Искусство"}', NULL, 8),
+  ('services', '{"en": "Services", "ru": "Услуги"}', NULL, 9),
+  ('other', '{"en": "Other", "ru": "Прочее"}', NULL, 10)
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO categories (parent_id, slug, names, attribute_schema, position)
+SELECT p.id, c.slug, c.names::jsonb, c.attribute_schema::jsonb, c.position
+FROM (VALUES
+  ('electronics', 'laptops', '{"en": "Laptops", "ru": "Ноутбуки"}',
+   '{"properties": {"ram_gb": {"type": "integer", "enum": [8, 16, 18, 24, 32, 36, 48, 64, 96, 128], "title": {"en": "RAM, GB", "ru": "Оперативная память, ГБ"}},
+                    "storage_gb": {"type": "integer", "minimum": 64, "maximum": 16384, "title": {"en": "Storage, GB", "ru": "Накопитель, ГБ"}},
+                    "screen_inches": {"type": "number", "minimum": 10, "maximum": 20, "title": {"en": "Screen, inches", "ru": "Диагональ, дюймы"}}},
+     "required": ["ram_gb"]}', 1),
+  ('electronics', 'smartphones', '{"en": "Smartphones", "ru": "Смартфоны"}',
+   '{"properties": {"storage_gb": {"type": "integer", "enum": [64, 128, 256, 512, 1024], "title": {"en": "Storage, GB", "ru": "Память, ГБ"}},
+                    "unlocked": {"type": "boolean", "title": {"en": "Carrier unlocked", "ru": "Без привязки к оператору"}}}}', 2),
+  ('electronics', 'audio', '{"en": "Audio", "ru": "Аудио"}', NULL, 3),
+  ('furniture', 'office-chairs', '{"en": "Office chairs", "ru": "Офисные кресла"}',
+   '{"properties": {"ergonomic": {"type": "boolean", "title": {"en": "Ergonomic", "ru": "Эргономичное"}},
+                    "max_load_kg": {"type": "integer", "minimum": 50, "maximum": 300, "title": {"en": "Max load, kg", "ru": "Макс. нагрузка, кг"}}}}', 1),
+  ('furniture', 'desks', '{"en": "Desks", "ru": "Столы"}',
+   '{"properties": {"height_adjustable": {"type": "boolean", "title": {"en": "Height adjustable", "ru": "Регулировка высоты"}}}}', 2),
+  ('vehicles', 'cars', '{"en": "Cars", "ru": "Легковые автомобили"}', NULL, 1),
+  ('vehicles', 'parts', '{"en": "Parts", "ru": "Запчасти"}', NULL, 2)
+) AS c(parent_slug, slug, names, attribute_schema, position)
+INNER JOIN categories p ON p.slug = c.parent_slug
+ON CONFLICT (slug) DO NOTHING;
+
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS attributes JSONB;
+
+-- Normalize free-text categories entered before the taxonomy existed.
+UPDATE requests r
+SET category = c.slug
+FROM categories c
+WHERE c.parent_id IS NULL
+  AND r.category IS NOT NULL
+  AND r.category <> c.slug
+  AND (lower(trim(r.category)) = c.slug
+       OR lower(trim(r.category)) = lower(c.names->>'en')
+       OR lower(trim(r.category)) = lower(c.names->>'ru'));
+
+UPDATE requests r
+SET subcategory = c.slug
+FROM categories c
+INNER JOIN categories p ON p.id = c.parent_id
+WHERE p.slug = r.category
+  AND r.subcategory IS NOT NULL
+  AND r.subcategory <> c.slug
+  AND (lower(trim(r.subcategory)) = c.slug
+       OR lower(trim(r.subcategory)) = lower(c.names->>'en')
+       OR lower(trim(r.subcategory)) = lower(c.names->>'ru'));
+
+-- Values matching no category would fail validation on the lot's next edit:
+-- unknown categories fall back to "other" and unknown subcategories are cleared.
+UPDATE requests r
+SET category = 'other', subcategory = NULL
+WHERE r.category IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM categories c WHERE c.parent_id IS NULL AND c.slug = r.category);
+
+UPDATE requests r
+SET subcategory = NULL
+WHERE r.subcategory IS NOT NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM categories c INNER JOIN categories p ON p.id = c.parent_id
+      WHERE p.slug = r.category AND c.slug = r.subcategory
+  );
+
+CREATE INDEX IF NOT EXISTS requests_category_idx ON requests(category, subcategory);
@@ -0,0 +1,22 @@
+ALTER TABLE requests
+    ADD COLUMN IF NOT EXISTS condition TEXT,
+    ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS unit TEXT NOT NULL DEFAULT 'pcs';
+
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_condition_chk;
+ALTER TABLE requests ADD CONSTRAINT requests_condition_chk
+    CHECK (condition IS NULL OR condition IN ('new', 'like-new', 'good', 'fair', 'any'));
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_quantity_chk;
+ALTER TABLE requests ADD CONSTRAINT requests_quantity_chk CHECK (quantity > 0);
+
+ALTER TABLE offers
+    ADD COLUMN IF NOT EXISTS quantity INTEGER,
+    ADD COLUMN IF NOT EXISTS unit_price NUMERIC(12,2);
+
+UPDATE offers o SET quantity = r.quantity FROM requests r WHERE r.id = o.request_id AND o.quantity IS NULL;
+
+ALTER TABLE offers ALTER COLUMN quantity SET NOT NULL;
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_quantity_chk;
+ALTER TABLE offers ADD CONSTRAINT offers_quantity_chk CHECK (quantity > 0);
+
+CREATE INDEX IF NOT EXISTS requests_condition_idx ON requests(condition);
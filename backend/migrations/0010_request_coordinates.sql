@@ -0,0 +1,17 @@
+ALTER TABLE requests
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_coordinates_chk;
+ALTER TABLE requests ADD CONSTRAINT requests_coordinates_chk CHECK (
+    (latitude IS NULL AND longitude IS NULL)
+    OR (latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180)
+);
+
+-- Radius queries pre-filter on a bounding box before computing distances.
+CREATE INDEX IF NOT EXISTS requests_coordinates_idx
+    ON requests(latitude, longitude) WHERE latitude IS NOT NULL;
+
+-- Lots saved before coordinates existed are geocoded from their city by a
+-- background job; geocoded_at marks the lots it has looked at.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS geocoded_at TIMESTAMPTZ;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    category TEXT,
+    subcategory TEXT,
+    condition TEXT,
+    min_quantity INTEGER,
+    near_latitude DOUBLE PRECISION,
+    near_longitude DOUBLE PRECISION,
+    radius_km DOUBLE PRECISION,
+    delivery TEXT NOT NULL DEFAULT 'instant' CHECK (delivery IN ('instant', 'daily')),
+    last_digest_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((near_latitude IS NULL) = (near_longitude IS NULL) AND (near_latitude IS NULL) = (radius_km IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches(user_id);
+
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    saved_search_id INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    notified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (saved_search_id, request_id)
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_matches_pending_idx
+    ON saved_search_matches(saved_search_id) WHERE notified_at IS NULL;
+
+-- Published lots are matched against saved searches asynchronously; this
+-- marks the ones the alert job has already handled.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS search_alerts_processed_at TIMESTAMPTZ;
+UPDATE requests SET search_alerts_processed_at = NOW() WHERE search_alerts_processed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS requests_search_alerts_pending_idx
+    ON requests(id) WHERE search_alerts_processed_at IS NULL AND status = 'open';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS request_watchers (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, request_id)
+);
+
+CREATE INDEX IF NOT EXISTS request_watchers_request_id_idx ON request_watchers(request_id);
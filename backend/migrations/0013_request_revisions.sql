@@ -0,0 +1,16 @@
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS request_revisions (
+    id SERIAL PRIMARY KEY,
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    material BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (request_id, revision)
+);
+
+-- Revision of the lot an offer was made against; offers made before a later
+-- material revision are flagged as outdated.
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS request_revision INTEGER NOT NULL DEFAULT 1;
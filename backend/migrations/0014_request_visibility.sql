@@ -0,0 +1,22 @@
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'public';
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_visibility_check;
+ALTER TABLE requests ADD CONSTRAINT requests_visibility_check CHECK (visibility IN ('public', 'private'));
+
+-- Secret token of the share link; anyone holding it can view a private lot
+-- and bid on it.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS share_token TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS requests_share_token_idx ON requests(share_token) WHERE share_token IS NOT NULL;
+
+-- Invitations are keyed by lower-cased email so sellers invited before they
+-- register gain access once they sign up with that address.
+CREATE TABLE IF NOT EXISTS request_invitations (
+    id SERIAL PRIMARY KEY,
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (request_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS request_invitations_user_id_idx ON request_invitations(user_id);
+CREATE INDEX IF NOT EXISTS request_invitations_email_idx ON request_invitations(email);
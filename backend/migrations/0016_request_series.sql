@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS request_series (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Deleting a template stops its series instead of deleting them, so their
+    -- history of published lots stays reachable.
+    template_id INTEGER REFERENCES request_templates(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    recurrence TEXT NOT NULL,
+    deadline_days INTEGER CHECK (deadline_days > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS request_series_user_id_idx ON request_series(user_id);
+CREATE INDEX IF NOT EXISTS request_series_due_idx ON request_series(next_run_at) WHERE active;
+
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS series_id INTEGER REFERENCES request_series(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS requests_series_id_idx ON requests(series_id) WHERE series_id IS NOT NULL;
@@ -0,0 +1,20 @@
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS view_count INTEGER NOT NULL DEFAULT 0;
+
+-- One row per viewer and lot, used only to deduplicate repeat views within a
+-- day; rows older than that are pruned by the server.
+CREATE TABLE IF NOT EXISTS request_views (
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    viewer_key TEXT NOT NULL,
+    viewed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (request_id, viewer_key)
+);
+
+CREATE INDEX IF NOT EXISTS request_views_viewed_at_idx ON request_views(viewed_at);
+
+-- Deduplicated views per lot and day, kept for the analytics chart.
+CREATE TABLE IF NOT EXISTS request_view_days (
+    request_id INTEGER NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (request_id, day)
+);
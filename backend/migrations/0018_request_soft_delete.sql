@@ -0,0 +1,3 @@
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS requests_deleted_at_idx ON requests(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,29 @@
+-- Negotiation rounds of an offer. Round 1 is the seller's original offer;
+-- each counter adds a round and rounds are never updated. The offer row
+-- mirrors the terms of its latest round.
+CREATE TABLE IF NOT EXISTS offer_rounds (
+    id SERIAL PRIMARY KEY,
+    offer_id INTEGER NOT NULL REFERENCES offers(id) ON DELETE CASCADE,
+    round INTEGER NOT NULL,
+    proposed_by TEXT NOT NULL CHECK (proposed_by IN ('seller', 'buyer')),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    price_amount NUMERIC(12,2) NOT NULL,
+    currency_code CHAR(3) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(12,2),
+    message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (offer_id, round)
+);
+
+ALTER TABLE offers
+    ADD COLUMN IF NOT EXISTS round INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS awaiting TEXT NOT NULL DEFAULT 'buyer';
+
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_awaiting_chk;
+ALTER TABLE offers ADD CONSTRAINT offers_awaiting_chk CHECK (awaiting IN ('seller', 'buyer'));
+
+INSERT INTO offer_rounds (offer_id, round, proposed_by, user_id, price_amount, currency_code, quantity, unit_price, message, created_at)
+SELECT o.id, 1, 'seller', o.seller_user_id, o.price_amount, o.currency_code, o.quantity, o.unit_price, o.message, o.created_at
+FROM offers o
+WHERE NOT EXISTS (SELECT 1 FROM offer_rounds r WHERE r.offer_id = o.id);
@@ -0,0 +1,5 @@
+-- Offers move from pending to accepted, declined (seller ends a negotiation),
+-- withdrawn (by the seller) or rejected (by the buyer, with an optional
+-- reason).
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
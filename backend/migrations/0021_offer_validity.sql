@@ -0,0 +1,6 @@
+-- Offers may be valid only until a given time; the server marks them expired
+-- afterwards.
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS valid_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS offers_valid_until_idx ON offers(valid_until)
+    WHERE valid_until IS NOT NULL AND status IN ('pending', 'backup');
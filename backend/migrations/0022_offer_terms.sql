@@ -0,0 +1,34 @@
+-- Structured terms sellers quote alongside the price: delivery time, shipping,
+-- warranty, payment terms and the condition of the offered items.
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS delivery_days INTEGER CHECK (delivery_days >= 0);
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS shipping_cost NUMERIC(12,2) CHECK (shipping_cost >= 0);
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS shipping_method TEXT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS warranty_months INTEGER CHECK (warranty_months >= 0);
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS payment_terms TEXT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS item_condition TEXT;
+
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_shipping_method_chk;
+ALTER TABLE offers ADD CONSTRAINT offers_shipping_method_chk
+    CHECK (shipping_method IN ('pickup', 'courier', 'post', 'freight'));
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_payment_terms_chk;
+ALTER TABLE offers ADD CONSTRAINT offers_payment_terms_chk
+    CHECK (payment_terms IN ('prepayment', 'on-delivery', 'escrow', 'deferred'));
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_item_condition_chk;
+ALTER TABLE offers ADD CONSTRAINT offers_item_condition_chk
+    CHECK (item_condition IN ('new', 'like-new', 'good', 'fair'));
+
+-- Rounds record the terms they proposed, so a deal is made on the terms of
+-- the round that was accepted.
+ALTER TABLE offer_rounds
+    ADD COLUMN IF NOT EXISTS delivery_days INTEGER CHECK (delivery_days >= 0),
+    ADD COLUMN IF NOT EXISTS shipping_cost NUMERIC(12,2) CHECK (shipping_cost >= 0),
+    ADD COLUMN IF NOT EXISTS shipping_method TEXT CHECK (shipping_method IN ('pickup', 'courier', 'post', 'freight')),
+    ADD COLUMN IF NOT EXISTS warranty_months INTEGER CHECK (warranty_months >= 0),
+    ADD COLUMN IF NOT EXISTS payment_terms TEXT CHECK (payment_terms IN ('prepayment', 'on-delivery', 'escrow', 'deferred')),
+    ADD COLUMN IF NOT EXISTS item_condition TEXT CHECK (item_condition IN ('new', 'like-new', 'good', 'fair'));
+
+UPDATE offer_rounds r
+SET delivery_days = o.delivery_days, shipping_cost = o.shipping_cost, shipping_method = o.shipping_method,
+    warranty_months = o.warranty_months, payment_terms = o.payment_terms, item_condition = o.item_condition
+FROM offers o
+WHERE r.offer_id = o.id AND r.round = o.round;
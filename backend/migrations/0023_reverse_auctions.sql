@@ -0,0 +1,20 @@
+-- Reverse auctions: sellers bid the price down in the open until the auction
+-- ends. A bid placed within the extension window pushes the end back by that
+-- window. The lowest bid wins, awarded automatically or on the buyer's
+-- confirmation.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'standard';
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_ends_at TIMESTAMPTZ;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_duration_hours INTEGER;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_min_decrement NUMERIC(12,2);
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_extension_minutes INTEGER;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_award TEXT;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_closed_at TIMESTAMPTZ;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS auction_winner_offer_id INTEGER REFERENCES offers(id) ON DELETE SET NULL;
+
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_mode_check;
+ALTER TABLE requests ADD CONSTRAINT requests_mode_check CHECK (mode IN ('standard', 'reverse_auction'));
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_auction_award_check;
+ALTER TABLE requests ADD CONSTRAINT requests_auction_award_check CHECK (auction_award IN ('auto', 'buyer'));
+
+CREATE INDEX IF NOT EXISTS requests_auction_ends_at_idx ON requests(auction_ends_at)
+    WHERE mode <> 'standard' AND auction_closed_at IS NULL;
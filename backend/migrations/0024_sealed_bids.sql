@@ -0,0 +1,12 @@
+-- Sealed-bid lots hide their offers from the buyer and from other sellers
+-- until the bid deadline; the buyer then sees them all at once.
+-- sealed_revealed_at records when the buyer was told.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS sealed_until TIMESTAMPTZ;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS sealed_duration_hours INTEGER;
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS sealed_revealed_at TIMESTAMPTZ;
+
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_mode_check;
+ALTER TABLE requests ADD CONSTRAINT requests_mode_check CHECK (mode IN ('standard', 'reverse_auction', 'sealed_bid'));
+
+CREATE INDEX IF NOT EXISTS requests_sealed_until_idx ON requests(sealed_until)
+    WHERE mode = 'sealed_bid' AND sealed_revealed_at IS NULL;
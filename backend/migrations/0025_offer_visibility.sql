@@ -0,0 +1,7 @@
+-- Who sees the offers on a lot besides its buyer: everyone in full (public),
+-- sellers their own offers plus the count and price range and everyone else
+-- the count (summary), or sellers only their own offers (private).
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS offer_visibility TEXT NOT NULL DEFAULT 'summary';
+
+ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_offer_visibility_check;
+ALTER TABLE requests ADD CONSTRAINT requests_offer_visibility_check CHECK (offer_visibility IN ('public', 'summary', 'private'));
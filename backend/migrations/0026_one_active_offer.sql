@@ -0,0 +1,13 @@
+-- A seller keeps at most one active offer per lot; offering again revises it.
+-- Earlier duplicates are withdrawn first, keeping each seller's latest offer.
+UPDATE offers o
+SET status = 'withdrawn', closed_at = NOW(), updated_at = NOW()
+WHERE o.status IN ('pending', 'backup')
+  AND EXISTS (
+      SELECT 1 FROM offers n
+      WHERE n.request_id = o.request_id AND n.seller_user_id = o.seller_user_id
+        AND n.status IN ('pending', 'backup') AND (n.created_at, n.id) > (o.created_at, o.id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS offers_active_seller_idx ON offers(request_id, seller_user_id)
+    WHERE status IN ('pending', 'backup');
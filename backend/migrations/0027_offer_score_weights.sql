@@ -0,0 +1,11 @@
+-- Offer comparison weights a buyer saved. GET /offers/compare starts from
+-- them instead of the default weights.
+CREATE TABLE IF NOT EXISTS offer_score_weights (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    price_weight DOUBLE PRECISION NOT NULL CHECK (price_weight >= 0),
+    delivery_weight DOUBLE PRECISION NOT NULL CHECK (delivery_weight >= 0),
+    rating_weight DOUBLE PRECISION NOT NULL CHECK (rating_weight >= 0),
+    experience_weight DOUBLE PRECISION NOT NULL CHECK (experience_weight >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (price_weight + delivery_weight + rating_weight + experience_weight > 0)
+);
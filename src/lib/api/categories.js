@@ -0,0 +1,6 @@
+import { apiFetch } from './client';
+
+export async function listCategories(lang) {
+  const search = lang ? `?lang=${encodeURIComponent(lang)}` : '';
+  return apiFetch(`/api/categories${search}`);
+}
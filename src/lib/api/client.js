@@ -20,6 +20,15 @@ const resolvePath = (path) => {
   return `/api${path.startsWith('/') ? '' : '/'}${path}`;
 };
 
+// ETags and bodies of the latest responses, keyed by URL. GETs revalidate
+// with If-None-Match so polling a resource that has not changed costs a 304,
+// and getCachedETag lets updates send If-Match for the version on screen.
+const etagCache = new Map();
+
+export function getCachedETag(path) {
+  return etagCache.get(resolvePath(path))?.etag;
+}
+
 export async function apiFetch(path, options = {}) {
   const {
     method = 'GET',
@@ -27,6 +36,7 @@ export async function apiFetch(path, options = {}) {
     headers = {},
     token,
     signal,
+    ifMatch,
   } = options;
 
   const url = resolvePath(path);
@@ -45,6 +55,14 @@ export async function apiFetch(path, options = {}) {
     finalHeaders.set('Authorization', `Bearer ${authToken}`);
   }
 
+  const cached = method === 'GET' ? etagCache.get(url) : undefined;
+  if (cached && !finalHeaders.has('If-None-Match')) {
+    finalHeaders.set('If-None-Match', cached.etag);
+  }
+  if (ifMatch) {
+    finalHeaders.set('If-Match', ifMatch);
+  }
+
   if (!finalHeaders.has('Accept')) {
     finalHeaders.set('Accept', 'application/json');
   }
@@ -56,6 +74,10 @@ export async function apiFetch(path, options = {}) {
     signal,
   });
 
+  if (response.status === 304 && cached) {
+    return cached.payload;
+  }
+
   const contentType = response.headers.get('Content-Type') || '';
   let payload;
 
@@ -76,5 +98,10 @@ export async function apiFetch(path, options = {}) {
     throw new APIError(message, response.status, payload);
   }
 
+  const etag = response.headers.get('ETag');
+  if (etag && (method === 'GET' || method === 'PATCH')) {
+    etagCache.set(url, { etag, payload });
+  }
+
   return payload;
 }
@@ -1,4 +1,4 @@
-import { apiFetch } from './client';
+import { apiFetch, getCachedETag } from './client';
 
 export function listDeals() {
   return apiFetch('/api/deals');
@@ -13,6 +13,7 @@ export function markDealShipped(id) {
   if (!id) throw new Error('deal id is required');
   return apiFetch(`/api/deals/${id}`, {
     method: 'PATCH',
+    ifMatch: getCachedETag(`/api/deals/${id}`),
     body: { action: 'mark_shipped' },
   });
 }
@@ -21,6 +22,7 @@ export function submitDealPayment(id) {
   if (!id) throw new Error('deal id is required');
   return apiFetch(`/api/deals/${id}`, {
     method: 'PATCH',
+    ifMatch: getCachedETag(`/api/deals/${id}`),
     body: { action: 'submit_payment' },
   });
 }
@@ -29,6 +31,7 @@ export function confirmDealCompletion(id) {
   if (!id) throw new Error('deal id is required');
   return apiFetch(`/api/deals/${id}`, {
     method: 'PATCH',
+    ifMatch: getCachedETag(`/api/deals/${id}`),
     body: { action: 'confirm_delivery' },
   });
 }
@@ -37,15 +40,26 @@ export function openDealDispute(id, reason) {
   if (!id) throw new Error('deal id is required');
   return apiFetch(`/api/deals/${id}`, {
     method: 'PATCH',
+    ifMatch: getCachedETag(`/api/deals/${id}`),
     body: { action: 'open_dispute', reason },
   });
 }
 
+export function cancelDeal(id, reason) {
+  if (!id) throw new Error('deal id is required');
+  return apiFetch(`/api/deals/${id}`, {
+    method: 'PATCH',
+    ifMatch: getCachedETag(`/api/deals/${id}`),
+    body: { action: 'cancel', reason },
+  });
+}
+
 export function rateDealCounterparty(id, rating, comment = '') {
   if (!id) throw new Error('deal id is required');
   if (typeof rating !== 'number') throw new Error('rating must be a number');
   return apiFetch(`/api/deals/${id}`, {
     method: 'PATCH',
+    ifMatch: getCachedETag(`/api/deals/${id}`),
     body: { action: 'rate_counterparty', rating, comment },
   });
 }
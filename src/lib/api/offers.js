@@ -7,6 +7,31 @@ export async function listOffers(requestId) {
   return apiFetch(`/api/requests/${requestId}/offers`);
 }
 
+export async function compareOffers(requestId, weights = {}) {
+  if (!requestId) {
+    throw new Error('Request id is required');
+  }
+  const query = new URLSearchParams();
+  ['price', 'delivery', 'rating', 'experience'].forEach((name) => {
+    if (typeof weights[name] === 'number') {
+      query.set(`${name}Weight`, String(weights[name]));
+    }
+  });
+  const search = query.toString();
+  return apiFetch(`/api/requests/${requestId}/offers/compare${search ? `?${search}` : ''}`);
+}
+
+export async function getOfferScoreWeights() {
+  return apiFetch('/api/me/offer-score-weights');
+}
+
+export async function saveOfferScoreWeights(weights) {
+  return apiFetch('/api/me/offer-score-weights', {
+    method: 'PATCH',
+    body: weights,
+  });
+}
+
 export async function createOffer(requestId, payload) {
   if (!requestId) {
     throw new Error('Request id is required');
@@ -36,11 +61,71 @@ export async function deleteOffer(offerId) {
   });
 }
 
-export async function acceptOffer(offerId) {
+export async function extendOffer(offerId, { validUntil, days } = {}) {
   if (!offerId) {
     throw new Error('Offer id is required');
   }
+  return apiFetch(`/api/offers/${offerId}/extend`, {
+    method: 'POST',
+    body: validUntil ? { validUntil } : { days },
+  });
+}
+
+export async function withdrawOffer(offerId) {
+  if (!offerId) {
+    throw new Error('Offer id is required');
+  }
+  return apiFetch(`/api/offers/${offerId}/withdraw`, {
+    method: 'POST',
+  });
+}
+
+export async function rejectOffer(offerId, { reason } = {}) {
+  if (!offerId) {
+    throw new Error('Offer id is required');
+  }
+  return apiFetch(`/api/offers/${offerId}/reject`, {
+    method: 'POST',
+    body: reason ? { reason } : undefined,
+  });
+}
+
+export async function acceptOffer(offerId, { round, keepBackups } = {}) {
+  if (!offerId) {
+    throw new Error('Offer id is required');
+  }
+  const body = {};
+  if (round) body.round = round;
+  if (keepBackups) body.keepBackups = true;
   return apiFetch(`/api/offers/${offerId}/accept`, {
     method: 'POST',
+    body: Object.keys(body).length ? body : undefined,
+  });
+}
+
+export async function counterOffer(offerId, payload) {
+  if (!offerId) {
+    throw new Error('Offer id is required');
+  }
+  return apiFetch(`/api/offers/${offerId}/counter`, {
+    method: 'POST',
+    body: payload,
   });
 }
+
+export async function declineOffer(offerId, { round } = {}) {
+  if (!offerId) {
+    throw new Error('Offer id is required');
+  }
+  return apiFetch(`/api/offers/${offerId}/decline`, {
+    method: 'POST',
+    body: round ? { round } : undefined,
+  });
+}
+
+export async function listOfferRounds(offerId) {
+  if (!offerId) {
+    throw new Error('Offer id is required');
+  }
+  return apiFetch(`/api/offers/${offerId}/rounds`);
+}
@@ -0,0 +1,24 @@
+import { apiFetch } from './client';
+
+export function listOrganizations() {
+  return apiFetch('/api/organizations');
+}
+
+export function createOrganization(name) {
+  return apiFetch('/api/organizations', { method: 'POST', body: { name } });
+}
+
+export function listOrganizationMembers(id) {
+  if (!id) throw new Error('organization id is required');
+  return apiFetch(`/api/organizations/${id}/members`);
+}
+
+export function addOrganizationMember(id, email) {
+  if (!id) throw new Error('organization id is required');
+  return apiFetch(`/api/organizations/${id}/members`, { method: 'POST', body: { email } });
+}
+
+export function removeOrganizationMember(id, userId) {
+  if (!id || !userId) throw new Error('organization id and user id are required');
+  return apiFetch(`/api/organizations/${id}/members/${userId}`, { method: 'DELETE' });
+}
@@ -1,4 +1,4 @@
-import { apiFetch } from './client';
+import { apiFetch, getCachedETag } from './client';
 
 export async function listRequests(params = {}) {
   const query = new URLSearchParams();
@@ -8,6 +8,24 @@ export async function listRequests(params = {}) {
   if (params.owner) {
     query.set('owner', params.owner);
   }
+  if (params.category) {
+    query.set('category', params.category);
+  }
+  if (params.subcategory) {
+    query.set('subcategory', params.subcategory);
+  }
+  if (params.condition) {
+    query.set('condition', params.condition);
+  }
+  if (typeof params.minQuantity === 'number') {
+    query.set('minQuantity', String(params.minQuantity));
+  }
+  if (params.near) {
+    query.set('near', `${params.near.latitude},${params.near.longitude}`);
+    if (typeof params.radiusKm === 'number') {
+      query.set('radiusKm', String(params.radiusKm));
+    }
+  }
   if (typeof params.limit === 'number') {
     query.set('limit', String(params.limit));
   }
@@ -15,11 +33,12 @@ export async function listRequests(params = {}) {
   return apiFetch(`/api/requests${search ? `?${search}` : ''}`);
 }
 
-export async function getRequest(id) {
+export async function getRequest(id, { share } = {}) {
   if (!id) {
     throw new Error('Request id is required');
   }
-  return apiFetch(`/api/requests/${id}`);
+  const search = share ? `?share=${encodeURIComponent(share)}` : '';
+  return apiFetch(`/api/requests/${id}${search}`);
 }
 
 export async function createRequest(payload) {
@@ -36,6 +55,7 @@ export async function updateRequest(id, payload) {
   return apiFetch(`/api/requests/${id}`, {
     method: 'PATCH',
     body: payload,
+    ifMatch: getCachedETag(`/api/requests/${id}`),
   });
 }
 
@@ -47,3 +67,174 @@ export async function deleteRequest(id) {
     method: 'DELETE',
   });
 }
+
+export async function restoreRequest(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/restore`, {
+    method: 'POST',
+  });
+}
+
+export async function listDeletedRequests() {
+  return apiFetch('/api/me/deleted-requests');
+}
+
+export async function publishRequest(id, payload = {}) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/publish`, {
+    method: 'POST',
+    body: payload,
+  });
+}
+
+export async function addRequestImage(id, payload) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/images`, {
+    method: 'POST',
+    body: payload,
+  });
+}
+
+export async function reorderRequestImages(id, imageIds) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/images/reorder`, {
+    method: 'POST',
+    body: { imageIds },
+  });
+}
+
+export async function updateRequestImage(id, imageId, payload) {
+  if (!id || !imageId) {
+    throw new Error('Request id and image id are required');
+  }
+  return apiFetch(`/api/requests/${id}/images/${imageId}`, {
+    method: 'PATCH',
+    body: payload,
+  });
+}
+
+export async function deleteRequestImage(id, imageId) {
+  if (!id || !imageId) {
+    throw new Error('Request id and image id are required');
+  }
+  return apiFetch(`/api/requests/${id}/images/${imageId}`, {
+    method: 'DELETE',
+  });
+}
+
+export async function watchRequest(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/watch`, { method: 'POST' });
+}
+
+export async function unwatchRequest(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/watch`, { method: 'DELETE' });
+}
+
+export async function listWatchlist() {
+  return apiFetch('/api/me/watchlist');
+}
+
+export async function getRequestHistory(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/history`);
+}
+
+export async function getRequestStats(id, { days } = {}) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  const query = days ? `?days=${days}` : '';
+  return apiFetch(`/api/requests/${id}/stats${query}`);
+}
+
+export async function getAuction(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/auction`);
+}
+
+export async function listRequestInvitations(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/invitations`);
+}
+
+export async function inviteSellers(id, { emails = [], userIds = [] } = {}) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/invitations`, {
+    method: 'POST',
+    body: { emails, userIds },
+  });
+}
+
+export async function deleteRequestInvitation(id, invitationId) {
+  if (!id || !invitationId) {
+    throw new Error('Request id and invitation id are required');
+  }
+  return apiFetch(`/api/requests/${id}/invitations/${invitationId}`, {
+    method: 'DELETE',
+  });
+}
+
+export async function createShareLink(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/share-link`, { method: 'POST' });
+}
+
+export async function revokeShareLink(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/share-link`, { method: 'DELETE' });
+}
+
+export async function importRequests(file, { dryRun = false } = {}) {
+  if (!file) {
+    throw new Error('File is required');
+  }
+  const body = new FormData();
+  body.append('file', file);
+  return apiFetch(`/api/requests/import${dryRun ? '?dryRun=true' : ''}`, {
+    method: 'POST',
+    body,
+  });
+}
+
+export async function duplicateRequest(id) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/duplicate`, { method: 'POST' });
+}
+
+export async function saveRequestAsTemplate(id, payload) {
+  if (!id) {
+    throw new Error('Request id is required');
+  }
+  return apiFetch(`/api/requests/${id}/template`, {
+    method: 'POST',
+    body: payload,
+  });
+}
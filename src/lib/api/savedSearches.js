@@ -0,0 +1,25 @@
+import { apiFetch } from './client';
+
+export function listSavedSearches() {
+  return apiFetch('/api/saved-searches');
+}
+
+export function createSavedSearch(payload) {
+  return apiFetch('/api/saved-searches', {
+    method: 'POST',
+    body: payload,
+  });
+}
+
+export function updateSavedSearch(id, payload) {
+  if (!id) throw new Error('saved search id is required');
+  return apiFetch(`/api/saved-searches/${id}`, {
+    method: 'PATCH',
+    body: payload,
+  });
+}
+
+export function deleteSavedSearch(id) {
+  if (!id) throw new Error('saved search id is required');
+  return apiFetch(`/api/saved-searches/${id}`, { method: 'DELETE' });
+}
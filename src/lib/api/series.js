@@ -0,0 +1,24 @@
+import { apiFetch } from './client';
+
+export function listSeries() {
+  return apiFetch('/api/request-series');
+}
+
+export function getSeries(id) {
+  if (!id) throw new Error('series id is required');
+  return apiFetch(`/api/request-series/${id}`);
+}
+
+export function createSeries(payload) {
+  return apiFetch('/api/request-series', { method: 'POST', body: payload });
+}
+
+export function updateSeries(id, payload) {
+  if (!id) throw new Error('series id is required');
+  return apiFetch(`/api/request-series/${id}`, { method: 'PATCH', body: payload });
+}
+
+export function deleteSeries(id) {
+  if (!id) throw new Error('series id is required');
+  return apiFetch(`/api/request-series/${id}`, { method: 'DELETE' });
+}
@@ -0,0 +1,29 @@
+import { apiFetch } from './client';
+
+export function listTemplates() {
+  return apiFetch('/api/request-templates');
+}
+
+export function getTemplate(id) {
+  if (!id) throw new Error('template id is required');
+  return apiFetch(`/api/request-templates/${id}`);
+}
+
+export function createTemplate(payload) {
+  return apiFetch('/api/request-templates', { method: 'POST', body: payload });
+}
+
+export function updateTemplate(id, payload) {
+  if (!id) throw new Error('template id is required');
+  return apiFetch(`/api/request-templates/${id}`, { method: 'PATCH', body: payload });
+}
+
+export function deleteTemplate(id) {
+  if (!id) throw new Error('template id is required');
+  return apiFetch(`/api/request-templates/${id}`, { method: 'DELETE' });
+}
+
+export function createRequestFromTemplate(id, payload = {}) {
+  if (!id) throw new Error('template id is required');
+  return apiFetch(`/api/request-templates/${id}/requests`, { method: 'POST', body: payload });
+}
@@ -380,12 +380,12 @@ const CreateLot = () => {
       if (formData.category) {
         payload.category = formData.category;
       }
+      if (formData.condition) {
+        payload.condition = formData.condition;
+      }
       if (formData.location) {
         payload.locationCity = formData.location;
       }
-      if (formData.tags?.length) {
-        payload.subcategory = formData.tags[0];
-      }
       if (Number.isFinite(Number(formData.duration)) && Number(formData.duration) > 0) {
         const deadline = new Date();
         deadline.setDate(deadline.getDate() + Number(formData.duration));
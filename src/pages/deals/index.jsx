@@ -9,6 +9,7 @@ import {
   submitDealPayment,
   confirmDealCompletion,
   openDealDispute,
+  cancelDeal,
   rateDealCounterparty,
 } from 'lib/api/deals';
 import { APIError } from 'lib/api/client';
@@ -139,6 +140,9 @@ const decorateDeal = (detail, currentUserId) => {
   if ((isBuyer || isSeller) && !['completed', 'in_dispute'].includes(detail.status)) {
     actions.push({ id: 'open_dispute', label: 'Открыть спор', primary: false });
   }
+  if ((isBuyer || isSeller) && ['awaiting_shipment', 'in_dispute'].includes(detail.status)) {
+    actions.push({ id: 'cancel', label: 'Отменить сделку', primary: false });
+  }
 
   const milestones = Array.isArray(detail.milestones)
     ? detail.milestones.map((milestone) => ({
@@ -272,6 +276,12 @@ const DealsPage = () => {
         case 'open_dispute':
           setDialog({ type: 'dispute', deal });
           break;
+        case 'cancel': {
+          if (window.confirm('Отменить сделку? Лот снова откроется для предложений.')) {
+            performDealUpdate(() => cancelDeal(deal.detail.id), 'Сделка отменена');
+          }
+          break;
+        }
         case 'rate_seller':
         case 'rate_buyer':
           setDialog({ type: 'rate', deal, target: action.meta?.target });
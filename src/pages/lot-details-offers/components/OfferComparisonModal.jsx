@@ -1,66 +1,94 @@
-import React, { useState } from 'react';
+import React, { useCallback, useEffect, useState } from 'react';
 import { createPortal } from 'react-dom';
 import Icon from 'components/AppIcon';
 import AppImage from 'components/AppImage';
+import { APIError } from 'lib/api/client';
+import { compareOffers, getOfferScoreWeights, saveOfferScoreWeights } from 'lib/api/offers';
 
-const OfferComparisonModal = ({ offers = [], onClose, onSelectOffer }) => {
-  const [selectedOffers, setSelectedOffers] = useState(offers.slice(0, 3));
-  const [sortBy, setSortBy] = useState('price');
+const DEFAULT_WEIGHTS = { price: 50, delivery: 20, rating: 20, experience: 10 };
 
-  const formatCurrency = (amount, currency = 'USD') => {
-    return new Intl.NumberFormat('en-US', {
-      style: 'currency',
-      currency: currency
-    }).format(amount);
-  };
+const CRITERIA = [
+  { key: 'price', label: 'Цена с доставкой' },
+  { key: 'delivery', label: 'Срок доставки' },
+  { key: 'rating', label: 'Рейтинг продавца' },
+  { key: 'experience', label: 'Опыт продавца' },
+];
 
-  const formatTimeAgo = (dateString) => {
-    const now = new Date();
-    const date = new Date(dateString);
-    const diffInHours = Math.floor((now - date) / (1000 * 60 * 60));
-    
-    if (diffInHours < 1) return 'Только что';
-    if (diffInHours < 24) return `${diffInHours}ч назад`;
-    
-    const diffInDays = Math.floor(diffInHours / 24);
-    return `${diffInDays}д назад`;
-  };
+const formatCurrency = (amount, currency = 'USD') => {
+  if (amount == null) return '—';
+  try {
+    return new Intl.NumberFormat('en-US', { style: 'currency', currency }).format(amount);
+  } catch (error) {
+    return `${amount} ${currency}`;
+  }
+};
 
-  const handleOfferSelection = (offer) => {
-    if (selectedOffers.find(o => o.id === offer.id)) {
-      setSelectedOffers(prev => prev.filter(o => o.id !== offer.id));
-    } else if (selectedOffers.length < 3) {
-      setSelectedOffers(prev => [...prev, offer]);
+// OfferComparisonModal shows the server-side ranking of the open offers on a
+// lot. Scores come from GET /offers/compare so they match what the API uses
+// for bestValueOfferId; the weight sliders start from the buyer's saved
+// weights and re-run the comparison.
+const OfferComparisonModal = ({ requestId, onClose, onSelectOffer }) => {
+  const [weights, setWeights] = useState(null);
+  const [comparison, setComparison] = useState(null);
+  const [loading, setLoading] = useState(true);
+  const [saving, setSaving] = useState(false);
+  const [saved, setSaved] = useState(false);
+  const [error, setError] = useState(null);
+
+  useEffect(() => {
+    getOfferScoreWeights()
+      .then((result) => setWeights({ ...DEFAULT_WEIGHTS, ...result }))
+      .catch(() => setWeights(DEFAULT_WEIGHTS));
+  }, []);
+
+  const loadComparison = useCallback(async () => {
+    if (!requestId || !weights) return;
+    setLoading(true);
+    setError(null);
+    try {
+      setComparison(await compareOffers(requestId, weights));
+    } catch (err) {
+      setError(err instanceof APIError ? err.message : 'Failed to compare offers.');
+    } finally {
+      setLoading(false);
     }
-  };
+  }, [requestId, weights]);
 
-  const getComparisonScore = (offer) => {
-    // Simple scoring algorithm based on price, rating, and response time
-    const priceScore = Math.max(0, 100 - (offer.price / 100)); // Lower price = higher score
-    const ratingScore = offer.seller.rating * 20; // Rating out of 5 * 20 = 100
-    const responseScore = offer.seller.responseTime.includes('< 2') ? 100 : 
-                         offer.seller.responseTime.includes('< 4') ? 80 : 60;
-    
-    return Math.round((priceScore + ratingScore + responseScore) / 3);
+  useEffect(() => {
+    loadComparison();
+  }, [loadComparison]);
+
+  const updateWeight = (key, value) => {
+    setSaved(false);
+    setWeights((prev) => ({ ...prev, [key]: value }));
   };
 
-  const getBestValue = () => {
-    return selectedOffers.reduce((best, current) => {
-      const currentScore = getComparisonScore(current);
-      const bestScore = getComparisonScore(best);
-      return currentScore > bestScore ? current : best;
-    }, selectedOffers[0]);
+  const handleSaveWeights = async () => {
+    setSaving(true);
+    setError(null);
+    try {
+      setWeights(await saveOfferScoreWeights(weights));
+      setSaved(true);
+    } catch (err) {
+      setError(err instanceof APIError ? err.message : 'Failed to save weights.');
+    } finally {
+      setSaving(false);
+    }
   };
 
+  const currency = comparison?.currencyCode || 'USD';
+  const scored = Array.isArray(comparison?.offers) ? comparison.offers : [];
+  const best = scored.find((item) => item.offer.id === comparison?.bestValueOfferId);
+
   const modalContent = (
     <div className="fixed inset-0 z-1000 flex items-center justify-center p-4 bg-black bg-opacity-50 backdrop-blur-subtle">
-      <div className="w-full max-w-6xl bg-surface rounded-xl shadow-xl max-h-[90vh] overflow-hidden">
+      <div className="w-full max-w-6xl bg-surface rounded-xl shadow-xl max-h-[90vh] overflow-hidden flex flex-col">
         {/* Header */}
         <div className="flex items-center justify-between p-6 border-b border-border">
           <div>
             <h2 className="text-2xl font-semibold text-text-primary">Сравнить предложения</h2>
             <p className="text-sm text-text-secondary mt-1">
-              Выберите до 3 предложений для сравнения ключевых характеристик и выбора лучшего варианта
+              Оценка 0–100 по каждому критерию относительно лучшего предложения, итог — взвешенное среднее
             </p>
           </div>
           <button
@@ -71,302 +99,180 @@ const OfferComparisonModal = ({ offers = [], onClose, onSelectOffer }) => {
           </button>
         </div>
 
-        <div className="flex flex-col lg:flex-row h-full">
-          {/* Offer Selection Sidebar */}
-          <div className="lg:w-80 border-r border-border bg-secondary-50">
-            <div className="p-4 border-b border-border">
-              <h3 className="font-semibold text-text-primary mb-2">Все предложения ({offers.length})</h3>
-              <div className="flex items-center space-x-2">
-                <Icon name="ArrowUpDown" size={16} className="text-text-secondary" />
-                <select
-                  value={sortBy}
-                  onChange={(e) => setSortBy(e.target.value)}
-                  className="text-sm border border-border rounded px-2 py-1 bg-background"
-                >
-                  <option value="price">Цена</option>
-                  <option value="rating">Рейтинг</option>
-                  <option value="recent">Дата публикации</option>
-                </select>
-              </div>
-            </div>
-            
-            <div className="overflow-y-auto max-h-96 p-2">
-              {offers.map((offer) => {
-                const isSelected = selectedOffers.find(o => o.id === offer.id);
-                const canSelect = selectedOffers.length < 3 || isSelected;
-                
-                return (
-                  <button
-                    key={offer.id}
-                    onClick={() => handleOfferSelection(offer)}
-                    disabled={!canSelect}
-                    className={`w-full text-left p-3 rounded-lg mb-2 transition-all duration-200 ${
-                      isSelected
-                        ? 'bg-primary-100 border-2 border-primary'
-                        : canSelect
-                        ? 'bg-surface border border-border hover:border-primary-200' :'bg-secondary-100 border border-border opacity-50 cursor-not-allowed'
-                    }`}
-                  >
-                    <div className="flex items-center justify-between mb-2">
-                      <div className="flex items-center space-x-2">
-                        <AppImage
-                          src={offer.seller.avatar}
-                          alt={offer.seller.name}
-                          className="w-8 h-8 rounded-full object-cover"
-                        />
-                        <div>
-                          <p className="font-medium text-sm">{offer.seller.name}</p>
-                          <div className="flex items-center space-x-1">
-                            <Icon name="Star" size={12} className="text-warning-500 fill-current" />
-                            <span className="text-xs text-text-secondary">{offer.seller.rating}</span>
-                          </div>
-                        </div>
-                      </div>
-                      {isSelected && (
-                        <Icon name="Check" size={16} className="text-primary" />
-                      )}
-                    </div>
-                    
-                    <div className="flex justify-between items-center">
-                      <span className="font-bold text-primary">
-                        {formatCurrency(offer.price)}
-                      </span>
-                      <span className="text-xs text-text-secondary">
-                        {formatTimeAgo(offer.submittedAt)}
-                      </span>
-                    </div>
-                  </button>
-                );
-              })}
-            </div>
+        <div className="flex flex-col lg:flex-row flex-1 overflow-hidden">
+          {/* Weights */}
+          <div className="lg:w-72 border-r border-border bg-secondary-50 p-4 space-y-4">
+            <h3 className="font-semibold text-text-primary">Важность критериев</h3>
+            {weights &&
+              CRITERIA.map((criterion) => (
+                <label key={criterion.key} className="block">
+                  <div className="flex justify-between text-sm mb-1">
+                    <span className="text-text-secondary">{criterion.label}</span>
+                    <span className="font-medium">{weights[criterion.key]}</span>
+                  </div>
+                  <input
+                    type="range"
+                    min="0"
+                    max="100"
+                    step="5"
+                    value={weights[criterion.key]}
+                    onChange={(e) => updateWeight(criterion.key, Number(e.target.value))}
+                    className="w-full"
+                  />
+                </label>
+              ))}
+            <button
+              onClick={handleSaveWeights}
+              disabled={!weights || saving}
+              className="btn-primary w-full py-2 rounded text-sm font-medium disabled:opacity-50"
+            >
+              {saved ? 'Сохранено' : 'Сохранить как мои настройки'}
+            </button>
+            <button
+              onClick={() => {
+                setSaved(false);
+                setWeights(DEFAULT_WEIGHTS);
+              }}
+              className="btn-secondary w-full py-2 rounded text-sm font-medium"
+            >
+              Сбросить
+            </button>
           </div>
 
-          {/* Comparison Table */}
-          <div className="flex-1 overflow-hidden">
-            {selectedOffers.length === 0 ? (
-              <div className="flex items-center justify-center h-full">
+          {/* Ranking */}
+          <div className="flex-1 overflow-y-auto">
+            {error && (
+              <div className="status-error m-4 rounded-lg p-4 flex items-center space-x-2">
+                <Icon name="AlertCircle" size={18} />
+                <span>{error}</span>
+              </div>
+            )}
+
+            {loading && !comparison ? (
+              <div className="flex items-center justify-center h-64 text-text-secondary">
+                <Icon name="Loader2" size={24} className="animate-spin mr-2" />
+                Сравниваем предложения…
+              </div>
+            ) : scored.length === 0 ? (
+              <div className="flex items-center justify-center h-64">
                 <div className="text-center">
                   <Icon name="BarChart3" size={48} className="text-secondary-300 mx-auto mb-4" />
-                  <h3 className="text-lg font-semibold text-text-primary mb-2">
-                    Выберите предложения для сравнения
-                  </h3>
-                  <p className="text-text-secondary">
-                    Выберите до 3 предложений из левой панели, чтобы увидеть их ключевые характеристики и помочь вам принять решение
-                  </p>
+                  <h3 className="text-lg font-semibold text-text-primary">Нет открытых предложений</h3>
                 </div>
               </div>
             ) : (
-              <div className="h-full overflow-y-auto">
-                {/* Best Value Banner */}
-                {selectedOffers.length > 1 && (
+              <>
+                {best && (
                   <div className="bg-success-50 border-b border-success-100 p-4">
                     <div className="flex items-center space-x-2">
                       <Icon name="Award" size={20} className="text-success-600" />
                       <span className="font-semibold text-success-800">
-                        Лучшее предложение: {getBestValue().seller.name} - {formatCurrency(getBestValue().price)}
+                        Лучшее предложение: {best.offer.sellerName} — {formatCurrency(best.landedCost, currency)}
                       </span>
                     </div>
                   </div>
                 )}
 
-                <div className="p-6">
-                  <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
-                    {selectedOffers.map((offer) => {
-                      const isBestValue = selectedOffers.length > 1 && getBestValue().id === offer.id;
-                      
-                      return (
-                        <div
-                          key={offer.id}
-                          className={`bg-surface border rounded-lg p-4 ${
-                            isBestValue ? 'border-success-300 bg-success-50' : 'border-border'
-                          }`}
-                        >
-                          {/* Header */}
-                          <div className="flex items-center justify-between mb-4">
-                            <div className="flex items-center space-x-3">
-                              <AppImage
-                                src={offer.seller.avatar}
-                                alt={offer.seller.name}
-                                className="w-10 h-10 rounded-full object-cover"
-                              />
-                              <div>
-                                <h4 className="font-semibold text-text-primary">{offer.seller.name}</h4>
-                                <div className="flex items-center space-x-1">
-                                  <Icon name="Star" size={14} className="text-warning-500 fill-current" />
-                                  <span className="text-sm text-text-secondary">{offer.seller.rating}</span>
-                                  {offer.seller.verified && (
-                                    <Icon name="CheckCircle" size={14} className="text-success-500" />
-                                  )}
-                                </div>
+                <div className="p-6 space-y-4">
+                  {scored.map((item) => {
+                    const { offer } = item;
+                    const isBestValue = offer.id === comparison?.bestValueOfferId;
+                    return (
+                      <div
+                        key={offer.id}
+                        className={`bg-surface border rounded-lg p-4 ${
+                          isBestValue ? 'border-success-300 bg-success-50' : 'border-border'
+                        }`}
+                      >
+                        <div className="flex items-center justify-between mb-4">
+                          <div className="flex items-center space-x-3">
+                            <span className="text-lg font-bold text-text-secondary">#{item.rank}</span>
+                            <AppImage
+                              src={offer.sellerAvatarUrl}
+                              alt={offer.sellerName}
+                              className="w-10 h-10 rounded-full object-cover"
+                            />
+                            <div>
+                              <h4 className="font-semibold text-text-primary">{offer.sellerName}</h4>
+                              <div className="flex items-center space-x-1 text-sm text-text-secondary">
+                                <Icon name="Star" size={14} className="text-warning-500 fill-current" />
+                                <span>{item.sellerRating != null ? item.sellerRating.toFixed(1) : '—'}</span>
+                                <span>· сделок: {item.sellerCompletedDeals}</span>
                               </div>
                             </div>
-                            {isBestValue && (
-                              <div className="bg-success-100 text-success-800 px-2 py-1 rounded text-xs font-medium">
-                                Лучшее предложение
-                              </div>
-                            )}
                           </div>
-
-                          {/* Price */}
-                          <div className="mb-4">
-                            <p className="text-2xl font-bold text-primary">
-                              {formatCurrency(offer.price)}
-                            </p>
-                            <p className="text-sm text-text-secondary">
-                              Оценка: {getComparisonScore(offer)}/100
-                            </p>
+                          <div className="text-right">
+                            <p className="text-2xl font-bold text-primary">{item.score}</p>
+                            <p className="text-xs text-text-secondary">из 100</p>
                           </div>
+                        </div>
 
-                          {/* Key Metrics */}
-                          <div className="space-y-3 mb-4">
-                            <div className="flex justify-between">
-                              <span className="text-sm text-text-secondary">Время ответа</span>
-                              <span className="text-sm font-medium">{offer.seller.responseTime}</span>
-                            </div>
-                            <div className="flex justify-between">
-                              <span className="text-sm text-text-secondary">Всего продаж</span>
-                              <span className="text-sm font-medium">{offer.seller.totalSales}</span>
-                            </div>
-                            <div className="flex justify-between">
-                              <span className="text-sm text-text-secondary">Местоположение</span>
-                              <span className="text-sm font-medium">{offer.seller.location}</span>
-                            </div>
+                        <div className="grid grid-cols-2 md:grid-cols-4 gap-3 mb-4 text-sm">
+                          <div>
+                            <p className="text-text-secondary">Итого с доставкой</p>
+                            <p className="font-medium">{formatCurrency(item.landedCost, currency)}</p>
                           </div>
-
-                          {/* Delivery Options */}
-                          <div className="mb-4">
-                            <h5 className="text-sm font-medium text-text-primary mb-2">Доставка</h5>
-                            <div className="space-y-1">
-                              {offer.deliveryOptions.map((option, index) => (
-                                <div key={index} className="flex items-center space-x-2 text-xs">
-                                  <Icon 
-                                    name={option.type === 'pickup' ? 'MapPin' : 'Truck'} 
-                                    size={12} 
-                                    className="text-text-secondary"
-                                  />
-                                  <span className="text-text-secondary">
-                                    {option.type === 'pickup' 
-                                      ? `Pickup: ${option.timeframe}`
-                                      : `Shipping: ${option.timeframe}`
-                                    }
-                                  </span>
-                                </div>
-                              ))}
-                            </div>
+                          <div>
+                            <p className="text-text-secondary">За единицу</p>
+                            <p className="font-medium">{formatCurrency(item.unitLandedCost, currency)}</p>
+                          </div>
+                          <div>
+                            <p className="text-text-secondary">Доставка</p>
+                            <p className="font-medium">
+                              {offer.deliveryDays != null ? `${offer.deliveryDays} дн.` : '—'}
+                            </p>
                           </div>
+                          <div>
+                            <p className="text-text-secondary">Гарантия</p>
+                            <p className="font-medium">
+                              {offer.warrantyMonths != null ? `${offer.warrantyMonths} мес.` : '—'}
+                            </p>
+                          </div>
+                        </div>
 
-                          {/* Key Features */}
-                          <div className="mb-4">
-                            <div className="flex flex-wrap gap-1">
-                              {offer.warranty && (
-                                <span className="px-2 py-1 bg-success-100 text-success-700 rounded text-xs">
-                                  Гарантия
-                                </span>
-                              )}
-                              {offer.additionalServices && offer.additionalServices.length > 0 && (
-                                <span className="px-2 py-1 bg-primary-100 text-primary-700 rounded text-xs">
-                                  +{offer.additionalServices.length} доп.
-                                </span>
-                              )}
-                              {offer.images && offer.images.length > 0 && (
-                                <span className="px-2 py-1 bg-secondary-100 text-secondary-700 rounded text-xs">
-                                  {offer.images.length} изображений
-                                </span>
-                              )}
+                        <div className="grid grid-cols-2 md:grid-cols-4 gap-3 mb-4">
+                          {CRITERIA.map((criterion) => (
+                            <div key={criterion.key}>
+                              <div className="flex justify-between text-xs text-text-secondary mb-1">
+                                <span>{criterion.label}</span>
+                                <span>{item.breakdown?.[criterion.key] ?? 0}</span>
+                              </div>
+                              <div className="h-1.5 bg-secondary-100 rounded">
+                                <div
+                                  className="h-1.5 bg-primary rounded"
+                                  style={{ width: `${item.breakdown?.[criterion.key] ?? 0}%` }}
+                                />
+                              </div>
                             </div>
-                          </div>
+                          ))}
+                        </div>
 
-                          {/* Actions */}
-                          <div className="space-y-2">
-                            <button
-                              onClick={() => onSelectOffer(offer)}
-                              className="btn-primary w-full py-2 rounded text-sm font-medium"
-                            >
-                              Принять предложение
-                            </button>
-                            <button
-                              onClick={() => console.log('Message seller:', offer.seller.name)}
-                              className="btn-secondary w-full py-2 rounded text-sm font-medium"
-                            >
-                              Написать продавцу
-                            </button>
+                        {Array.isArray(item.warnings) && item.warnings.length > 0 && (
+                          <div className="flex flex-wrap gap-1 mb-4">
+                            {item.warnings.map((warning) => (
+                              <span
+                                key={warning}
+                                className="px-2 py-1 bg-warning-50 text-warning-600 rounded text-xs"
+                              >
+                                {warning}
+                              </span>
+                            ))}
                           </div>
-                        </div>
-                      );
-                    })}
-                  </div>
+                        )}
 
-                  {/* Detailed Comparison Table */}
-                  {selectedOffers.length > 1 && (
-                    <div className="mt-8">
-                      <h3 className="text-lg font-semibold text-text-primary mb-4">Детальное сравнение</h3>
-                      <div className="overflow-x-auto">
-                        <table className="w-full border border-border rounded-lg">
-                          <thead className="bg-secondary-50">
-                            <tr>
-                              <th className="text-left p-3 border-b border-border font-medium text-text-primary">
-                                Особенности
-                              </th>
-                              {selectedOffers.map((offer) => (
-                                <th key={offer.id} className="text-left p-3 border-b border-border font-medium text-text-primary">
-                                  {offer.seller.name}
-                                </th>
-                              ))}
-                            </tr>
-                          </thead>
-                          <tbody>
-                            <tr>
-                              <td className="p-3 border-b border-border font-medium">Цена</td>
-                              {selectedOffers.map((offer) => (
-                                <td key={offer.id} className="p-3 border-b border-border">
-                                  <span className="font-bold text-primary">
-                                    {formatCurrency(offer.price)}
-                                  </span>
-                                </td>
-                              ))}
-                            </tr>
-                            <tr>
-                              <td className="p-3 border-b border-border font-medium">Рейтинг</td>
-                              {selectedOffers.map((offer) => (
-                                <td key={offer.id} className="p-3 border-b border-border">
-                                  <div className="flex items-center space-x-1">
-                                    <Icon name="Star" size={14} className="text-warning-500 fill-current" />
-                                    <span>{offer.seller.rating}</span>
-                                  </div>
-                                </td>
-                              ))}
-                            </tr>
-                            <tr>
-                              <td className="p-3 border-b border-border font-medium">Время ответа</td>
-                              {selectedOffers.map((offer) => (
-                                <td key={offer.id} className="p-3 border-b border-border">
-                                  {offer.seller.responseTime}
-                                </td>
-                              ))}
-                            </tr>
-                            <tr>
-                              <td className="p-3 border-b border-border font-medium">Гарантия</td>
-                              {selectedOffers.map((offer) => (
-                                <td key={offer.id} className="p-3 border-b border-border">
-                                  {offer.warranty || 'Not specified'}
-                                </td>
-                              ))}
-                            </tr>
-                            <tr>
-                              <td className="p-3 font-medium">Таймлайн</td>
-                              {selectedOffers.map((offer) => (
-                                <td key={offer.id} className="p-3">
-                                  {offer.timeline}
-                                </td>
-                              ))}
-                            </tr>
-                          </tbody>
-                        </table>
+                        {onSelectOffer && (
+                          <button
+                            onClick={() => onSelectOffer(offer)}
+                            className="btn-primary w-full md:w-auto px-4 py-2 rounded text-sm font-medium"
+                          >
+                            Принять предложение
+                          </button>
+                        )}
                       </div>
-                    </div>
-                  )}
+                    );
+                  })}
                 </div>
-              </div>
+              </>
             )}
           </div>
         </div>
@@ -377,4 +283,4 @@ const OfferComparisonModal = ({ offers = [], onClose, onSelectOffer }) => {
   return createPortal(modalContent, document.body);
 };
 
-export default OfferComparisonModal;
\ No newline at end of file
+export default OfferComparisonModal;
@@ -10,6 +10,7 @@ import { acceptOffer, createOffer, deleteOffer, listOffers, updateOffer } from '
 import MakeOfferModal from './components/MakeOfferModal';
 import OffersList from './components/OffersList';
 import ChatModal from './components/ChatModal';
+import OfferComparisonModal from './components/OfferComparisonModal';
 import { listOfferMessages, sendOfferMessage } from 'lib/api/messages';
 import { uploadImage as uploadImageFile } from 'lib/api/uploads';
 
@@ -51,6 +52,7 @@ const LotDetailsOffers = () => {
   const [chatOpen, setChatOpen] = useState(false);
   const [loadingChat, setLoadingChat] = useState(false);
   const [sendingChat, setSendingChat] = useState(false);
+  const [comparisonOpen, setComparisonOpen] = useState(false);
 
   const requestIdParam = searchParams.get('id');
   const requestId = requestIdParam ? Number(requestIdParam) : NaN;
@@ -85,7 +87,7 @@ const LotDetailsOffers = () => {
     setLoadingOffers(true);
     try {
       const data = await listOffers(requestId);
-      const normalized = Array.isArray(data) ? data : [];
+      const normalized = Array.isArray(data?.offers) ? data.offers : [];
       normalized.sort((a, b) => new Date(b.createdAt) - new Date(a.createdAt));
       setOffers(normalized);
     } catch (err) {
@@ -447,9 +449,20 @@ const LotDetailsOffers = () => {
           <div className="space-y-4">
             <div className="flex items-center justify-between">
               <h2 className="text-2xl font-semibold text-text-primary">Предложения</h2>
-              {!loadingOffers && (
-                <span className="text-sm text-text-secondary">{offers.length} предложений</span>
-              )}
+              <div className="flex items-center space-x-3">
+                {!loadingOffers && (
+                  <span className="text-sm text-text-secondary">{offers.length} предложений</span>
+                )}
+                {isOwner && offers.length > 1 && (
+                  <button
+                    onClick={() => setComparisonOpen(true)}
+                    className="btn-secondary px-3 py-1.5 rounded-lg text-sm font-medium flex items-center space-x-1"
+                  >
+                    <Icon name="BarChart3" size={16} />
+                    <span>Сравнить</span>
+                  </button>
+                )}
+              </div>
             </div>
 
             {offersError && (
@@ -484,6 +497,17 @@ const LotDetailsOffers = () => {
         onSubmit={handleSubmitOffer}
       />
 
+      {comparisonOpen && (
+        <OfferComparisonModal
+          requestId={requestId}
+          onClose={() => setComparisonOpen(false)}
+          onSelectOffer={async (offer) => {
+            setComparisonOpen(false);
+            await handleAcceptOffer(offer.id);
+          }}
+        />
+      )}
+
       <ChatModal
         open={chatOpen}
         offer={chatOffer}